@@ -0,0 +1,150 @@
+package godruid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// LegacyAuthCookieName cookie name for Client.AuthToken
+const LegacyAuthCookieName = "skylight-aaa"
+
+// Authenticator decorate outgoing druid requests with credentials
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthRefresher authenticator that can refresh its credentials,
+// a request responded with 401 is sent once again after Refresh succeeded.
+type AuthRefresher interface {
+	Authenticator
+	Refresh(ctx context.Context) error
+}
+
+// BasicAuth HTTP Basic authentication, for druid basic-security extension
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate set the basic authorization header
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerToken static bearer token authentication
+type BearerToken string
+
+// Authenticate set the bearer authorization header
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// CookieAuth authentication with a custom cookie
+type CookieAuth struct {
+	Name  string
+	Value string
+}
+
+// Authenticate add the cookie
+func (a CookieAuth) Authenticate(req *http.Request) error {
+	req.AddCookie(&http.Cookie{Name: a.Name, Value: a.Value})
+	return nil
+}
+
+// HeaderAuth authentication with a custom header
+type HeaderAuth struct {
+	Name  string
+	Value string
+}
+
+// Authenticate set the header
+func (a HeaderAuth) Authenticate(req *http.Request) error {
+	req.Header.Set(a.Name, a.Value)
+	return nil
+}
+
+// TokenSource fetch a new token
+type TokenSource func(ctx context.Context) (string, error)
+
+// TokenSourceAuth bearer token authentication with the token fetched from a token source,
+// the token is cached and fetched again when druid responds 401.
+// Concurrent refreshes share one fetch from the source.
+type TokenSourceAuth struct {
+	Source TokenSource
+	// Header header name of the token, default to `Authorization` with `Bearer ` prefixed
+	Header string
+
+	mu         sync.Mutex
+	token      string
+	refreshing *tokenRefresh
+}
+
+// tokenRefresh a fetch from the token source in flight
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewTokenSourceAuth new token source authenticator
+func NewTokenSourceAuth(source TokenSource) *TokenSourceAuth {
+	return &TokenSourceAuth{Source: source}
+}
+
+// Authenticate set the cached token, fetch one when no token cached.
+func (a *TokenSourceAuth) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	token := a.token
+	a.mu.Unlock()
+	if token == "" {
+		if err := a.Refresh(req.Context()); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		token = a.token
+		a.mu.Unlock()
+	}
+
+	if a.Header == "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Set(a.Header, token)
+	}
+	return nil
+}
+
+// Refresh fetch a new token from the source, or wait for the fetch in flight started by another caller.
+func (a *TokenSourceAuth) Refresh(ctx context.Context) error {
+	if a.Source == nil {
+		return errors.New("token source is nil")
+	}
+
+	a.mu.Lock()
+	if r := a.refreshing; r != nil {
+		a.mu.Unlock()
+		select {
+		case <-r.done:
+			return r.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	r := &tokenRefresh{done: make(chan struct{})}
+	a.refreshing = r
+	a.mu.Unlock()
+
+	token, err := a.Source(ctx)
+
+	a.mu.Lock()
+	if err == nil {
+		a.token = token
+	}
+	r.err = err
+	a.refreshing = nil
+	a.mu.Unlock()
+	close(r.done)
+	return err
+}
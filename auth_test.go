@@ -0,0 +1,147 @@
+package godruid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuthenticator(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		authToken     string
+		checkAuth     func(r *http.Request) bool
+	}{
+		{
+			"legacy-token",
+			nil, "t0",
+			func(r *http.Request) bool {
+				c, err := r.Cookie(LegacyAuthCookieName)
+				return err == nil && c.Value == "t0"
+			},
+		},
+		{
+			"basic",
+			BasicAuth{Username: "admin", Password: "pass"}, "t0",
+			func(r *http.Request) bool {
+				user, pass, ok := r.BasicAuth()
+				_, err := r.Cookie(LegacyAuthCookieName)
+				return ok && user == "admin" && pass == "pass" && err != nil
+			},
+		},
+		{
+			"bearer",
+			BearerToken("t1"), "",
+			func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer t1" },
+		},
+		{
+			"cookie",
+			CookieAuth{Name: "sid", Value: "t2"}, "",
+			func(r *http.Request) bool {
+				c, err := r.Cookie("sid")
+				return err == nil && c.Value == "t2"
+			},
+		},
+		{
+			"header",
+			HeaderAuth{Name: "X-Api-Key", Value: "t3"}, "",
+			func(r *http.Request) bool { return r.Header.Get("X-Api-Key") == "t3" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.checkAuth(r) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			client := &Client{
+				Url:           server.URL,
+				HttpClient:    server.Client(),
+				Authenticator: tt.authenticator,
+				AuthToken:     tt.authToken,
+			}
+			if _, err := client.QueryRaw([]byte(`{}`)); err != nil {
+				t.Errorf("Client.QueryRaw() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenSourceAuth_refresh(t *testing.T) {
+	validToken := "t1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var fetched int
+	auth := NewTokenSourceAuth(func(ctx context.Context) (string, error) {
+		fetched++
+		return fmt.Sprintf("t%d", fetched), nil
+	})
+	client := &Client{Url: server.URL, HttpClient: server.Client(), Authenticator: auth}
+
+	for i, wantFetched := range []int{1, 1, 2} {
+		if i == 2 {
+			validToken = "t2"
+		}
+		if _, err := client.QueryRaw([]byte(`{}`)); err != nil {
+			t.Errorf("Client.QueryRaw() error = %v", err)
+		}
+		if fetched != wantFetched {
+			t.Errorf("TokenSourceAuth fetched = %d, want %d", fetched, wantFetched)
+		}
+	}
+}
+
+func TestTokenSourceAuth_Refresh_concurrent(t *testing.T) {
+	var fetched int32
+	release := make(chan struct{})
+	auth := NewTokenSourceAuth(func(ctx context.Context) (string, error) {
+		n := atomic.AddInt32(&fetched, 1)
+		<-release
+		return fmt.Sprintf("t%d", n), nil
+	})
+
+	var wg, started sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			errs <- auth.Refresh(context.Background())
+		}()
+	}
+	// the fetch is blocked until all callers are refreshing
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("TokenSourceAuth.Refresh() error = %v", err)
+		}
+	}
+	if fetched != 1 || auth.token != "t1" {
+		t.Errorf("TokenSourceAuth fetched = %d, token = %s, want 1 fetch of t1", fetched, auth.token)
+	}
+}
@@ -0,0 +1,281 @@
+package godruid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// HealthCheckPath druid api path for node health probing
+const HealthCheckPath = "/status/health"
+
+// DefaultHealthCheckInterval default interval between two rounds of broker health probing
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// DefaultHealthCheckTimeout default timeout for one broker health probe
+const DefaultHealthCheckTimeout = 3 * time.Second
+
+// BrokerBalance strategy for choosing a broker from healthy ones
+type BrokerBalance int
+
+const (
+	// BalanceRoundRobin choose healthy brokers in turn
+	BalanceRoundRobin BrokerBalance = iota
+	// BalanceLeastInflight choose the healthy broker with the fewest in-flight requests
+	BalanceLeastInflight
+)
+
+// BrokerPool pool of druid broker/router urls with background health checks and failover.
+// A pool is safe for concurrent use, set options before calling Start.
+type BrokerPool struct {
+	Balance             BrokerBalance
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	HttpClient          *http.Client
+
+	brokers  []*broker
+	next     uint32
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+type broker struct {
+	url      string
+	down     int32
+	inflight int32
+}
+
+// NewBrokerPool create a broker pool with the given base urls, all brokers are assumed healthy until probed.
+func NewBrokerPool(urls ...string) *BrokerPool {
+	p := &BrokerPool{stopCh: make(chan struct{})}
+	for _, u := range urls {
+		p.brokers = append(p.brokers, &broker{url: strings.TrimRight(u, "/")})
+	}
+	return p
+}
+
+// Start probe the brokers' health in background until Close is called.
+func (p *BrokerPool) Start() {
+	interval := p.HealthCheckInterval
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.CheckHealth(context.Background())
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stop the background health checks.
+func (p *BrokerPool) Close() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.wg.Wait()
+}
+
+// URLs all broker urls in the pool
+func (p *BrokerPool) URLs() []string {
+	ret := []string{}
+	for _, b := range p.brokers {
+		ret = append(ret, b.url)
+	}
+	return ret
+}
+
+// Healthy broker urls considered healthy now
+func (p *BrokerPool) Healthy() []string {
+	ret := []string{}
+	for _, b := range p.brokers {
+		if b.healthy() {
+			ret = append(ret, b.url)
+		}
+	}
+	return ret
+}
+
+// CheckHealth probe `/status/health` of all brokers once.
+func (p *BrokerPool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, b := range p.brokers {
+		wg.Add(1)
+		go func(b *broker) {
+			defer wg.Done()
+			b.setHealthy(p.probe(ctx, b))
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (p *BrokerPool) probe(ctx context.Context, b *broker) bool {
+	timeout := p.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequest(http.MethodGet, b.url+HealthCheckPath, nil)
+	if err != nil {
+		return false
+	}
+	httpClient := p.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return err == nil && resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) == "true"
+}
+
+// pick choose a broker not in tried, healthy brokers are preferred.
+func (p *BrokerPool) pick(tried map[*broker]bool) *broker {
+	if b := p.pickFrom(tried, true); b != nil {
+		return b
+	}
+	// no healthy one left, trying an unhealthy broker is better than failing directly.
+	return p.pickFrom(tried, false)
+}
+
+func (p *BrokerPool) pickFrom(tried map[*broker]bool, healthyOnly bool) *broker {
+	candidates := []*broker{}
+	for _, b := range p.brokers {
+		if !tried[b] && (!healthyOnly || b.healthy()) {
+			candidates = append(candidates, b)
+		}
+	}
+	count := len(candidates)
+	if count == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint32(&p.next, 1) % uint32(count))
+	chosen := candidates[start]
+	if p.Balance == BalanceLeastInflight {
+		for i := 1; i < count; i++ {
+			b := candidates[(start+i)%count]
+			if atomic.LoadInt32(&b.inflight) < atomic.LoadInt32(&chosen.inflight) {
+				chosen = b
+			}
+		}
+	}
+	return chosen
+}
+
+func (b *broker) healthy() bool { return atomic.LoadInt32(&b.down) == 0 }
+
+func (b *broker) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&b.down, 0)
+	} else {
+		atomic.StoreInt32(&b.down, 1)
+	}
+}
+
+// inflightBody release the broker's in-flight slot when the response body is closed.
+type inflightBody struct {
+	io.ReadCloser
+	once sync.Once
+	b    *broker
+}
+
+func (r *inflightBody) Close() error {
+	r.once.Do(func() { atomic.AddInt32(&r.b.inflight, -1) })
+	return r.ReadCloser.Close()
+}
+
+// request send the request to brokers in the pool, failover to next broker on connection errors, timeouts,
+// the statuses 502 and 503, and the 504 of gateways. Other statuses such as druid's 500 query errors and
+// druid's 504 query timeouts are returned directly, for the query would fail on every broker.
+func (p *BrokerPool) request(ctx context.Context, send func(baseURL string) (*http.Response, error)) (*http.Response, error) {
+	tried := map[*broker]bool{}
+	var lastResp *http.Response
+	var lastErr error
+	for {
+		b := p.pick(tried)
+		if b == nil {
+			break
+		}
+		tried[b] = true
+		if lastResp != nil {
+			lastResp.Body.Close()
+			lastResp = nil
+		}
+
+		atomic.AddInt32(&b.inflight, 1)
+		resp, err := send(b.url)
+		if err != nil {
+			atomic.AddInt32(&b.inflight, -1)
+			lastErr = err
+			if ctx.Err() != nil || !isBrokerFailure(err) {
+				return nil, err
+			}
+			b.setHealthy(false)
+			continue
+		}
+		resp.Body = &inflightBody{ReadCloser: resp.Body, b: b}
+		if !isFailoverStatus(resp) {
+			return resp, nil
+		}
+		if resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable {
+			b.setHealthy(false)
+		}
+		lastResp, lastErr = resp, nil
+	}
+
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no broker in pool")
+	}
+	return nil, lastErr
+}
+
+// isFailoverStatus whether the response status means the broker or the node behind the router is unavailable,
+// a 504 with druid error body is druid's query timeout, which is not.
+func isFailoverStatus(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	case http.StatusGatewayTimeout:
+		return !readDruidError(resp).fromDruid()
+	default:
+		return false
+	}
+}
+
+// isBrokerFailure whether the request error means the broker is unreachable or too slow.
+func isBrokerFailure(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
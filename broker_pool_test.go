@@ -0,0 +1,153 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestBroker(status int, health string, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == HealthCheckPath {
+			w.Write([]byte(health))
+			return
+		}
+		*hits++
+		w.WriteHeader(status)
+		w.Write([]byte(`[]`))
+	}))
+}
+
+func TestBrokerPool_CheckHealth(t *testing.T) {
+	var hits int
+	up := newTestBroker(http.StatusOK, "true", &hits)
+	defer up.Close()
+	sick := newTestBroker(http.StatusOK, "false", &hits)
+	defer sick.Close()
+	gone := newTestBroker(http.StatusOK, "true", &hits)
+	gone.Close()
+
+	pool := NewBrokerPool(up.URL, sick.URL, gone.URL)
+	pool.CheckHealth(context.Background())
+	if got := pool.Healthy(); !reflect.DeepEqual(got, []string{up.URL}) {
+		t.Errorf("BrokerPool.Healthy() = %v, want %v", got, []string{up.URL})
+	}
+}
+
+func TestBrokerPool_request(t *testing.T) {
+	var okHits, failHits int
+	ok := newTestBroker(http.StatusOK, "true", &okHits)
+	defer ok.Close()
+	unavailable := newTestBroker(http.StatusServiceUnavailable, "true", &failHits)
+	defer unavailable.Close()
+	gone := newTestBroker(http.StatusOK, "true", &failHits)
+	gone.Close()
+	timeout := newTestBroker(http.StatusGatewayTimeout, "true", &failHits)
+	defer timeout.Close()
+
+	tests := []struct {
+		name       string
+		urls       []string
+		wantStatus int
+		wantErr    bool
+	}{
+		{"single", []string{ok.URL}, http.StatusOK, false},
+		{"failover-5xx", []string{unavailable.URL, ok.URL}, http.StatusOK, false},
+		{"failover-refused", []string{gone.URL, ok.URL}, http.StatusOK, false},
+		{"failover-504", []string{timeout.URL, ok.URL}, http.StatusOK, false},
+		{"all-5xx", []string{unavailable.URL}, http.StatusServiceUnavailable, false},
+		{"all-refused", []string{gone.URL}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{Brokers: NewBrokerPool(tt.urls...), HttpClient: http.DefaultClient}
+			for i := 0; i < len(tt.urls)*2; i++ {
+				resp, err := client.queryWithRetry(context.Background(), DefaultEndPoint, "", []byte(`{}`))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Client.queryWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("Client.queryWithRetry() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			for _, b := range client.Brokers.brokers {
+				if b.inflight != 0 {
+					t.Errorf("broker %s inflight = %d, want 0", b.url, b.inflight)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerPool_request_queryError(t *testing.T) {
+	var hits int
+	first := newTestBroker(http.StatusInternalServerError, "true", &hits)
+	defer first.Close()
+	second := newTestBroker(http.StatusInternalServerError, "true", &hits)
+	defer second.Close()
+
+	client := &Client{Brokers: NewBrokerPool(first.URL, second.URL), HttpClient: http.DefaultClient}
+	resp, err := client.queryWithRetry(context.Background(), DefaultEndPoint, "", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Client.queryWithRetry() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || hits != 1 {
+		t.Errorf("Client.queryWithRetry() status = %d, hits = %d, want 500 from 1 broker", resp.StatusCode, hits)
+	}
+}
+
+func TestBrokerPool_request_queryTimeout(t *testing.T) {
+	var hits int
+	timeout := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(`{"error":"Query timeout","errorClass":"org.apache.druid.query.QueryTimeoutException"}`))
+	})
+	var urls []string
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(timeout)
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	client := &Client{Brokers: NewBrokerPool(urls...), HttpClient: http.DefaultClient, Retry: policy}
+	query := &QueryTimeseries{DataSource: TableName("wiki"), Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+	err := client.QueryContext(context.Background(), query)
+	if druidErr, ok := err.(*DruidError); !ok || !druidErr.Timeout() {
+		t.Errorf("Client.QueryContext() error = %v, want druid query timeout", err)
+	}
+	if hits != 1 {
+		t.Errorf("Client.QueryContext() executed %d times, want 1", hits)
+	}
+}
+
+func TestBrokerPool_pick(t *testing.T) {
+	pool := NewBrokerPool("http://a", "http://b", "http://c")
+	pool.brokers[1].setHealthy(false)
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[pool.pick(nil).url]++
+	}
+	if !reflect.DeepEqual(seen, map[string]int{"http://a": 2, "http://c": 2}) {
+		t.Errorf("BrokerPool.pick() round robin = %v", seen)
+	}
+
+	pool.Balance = BalanceLeastInflight
+	pool.brokers[0].inflight = 3
+	for i := 0; i < 3; i++ {
+		if got := pool.pick(nil).url; got != "http://c" {
+			t.Errorf("BrokerPool.pick() least inflight = %s, want http://c", got)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package godruid
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
+	"sync"
 	"time"
 )
 
@@ -20,6 +24,22 @@ const CacheThresholdLower = 3
 // URLUpdateErrReg match regexp for url updating
 const URLUpdateErrReg = `connection\s+refused`
 
+// queryAPI druid query api
+type queryAPI struct {
+	// path relative to Client.EndPoint
+	path string
+	// idKey query context key of the query id
+	idKey string
+}
+
+var (
+	nativeAPI = queryAPI{path: "", idKey: QUERYID}
+	sqlAPI    = queryAPI{path: "/sql", idKey: SQLQUERYID}
+)
+
+// CancelTimeout timeout for the `DELETE /druid/v2/{queryId}` request sent when a query's context is done
+const CancelTimeout = 5 * time.Second
+
 type CacheSelectQuery struct {
 	Target     string      `json:"target"`
 	Conditions []Condition `json:"conditions,omitempty"`
@@ -69,7 +89,8 @@ type LoggerInterface interface {
 }
 
 // URLUpdater updater for druid api base url
-// 	some druid server deployed with cluster, url maybe changed when cluster node migrate or added/deleted.
+//
+//	some druid server deployed with cluster, url maybe changed when cluster node migrate or added/deleted.
 type URLUpdater func() (string, error)
 
 // EmptyLogger no log ops logger
@@ -86,23 +107,72 @@ func (l *EmptyLogger) Debugf(format string, v ...interface{}) {}
 func (l *EmptyLogger) Errorf(format string, v ...interface{}) {}
 func (l *EmptyLogger) Warnf(format string, v ...interface{})  {}
 
+// Client druid client, it is safe for concurrent use by multiple goroutines,
+// but the fields should not be modified after the first query.
 type Client struct {
-	Url          string
-	URLUpdater   URLUpdater
-	EndPoint     string
-	DataSource   string
-	AuthToken    string
-	Debug        bool
-	LastRequest  string
-	LastResponse string
-	HttpClient   *http.Client
-	Logger       LoggerInterface
-	ResultCache  CacheAdapter
-	GroupByCache GroupByCacheAdapter
+	Url           string
+	URLUpdater    URLUpdater
+	Brokers       *BrokerPool // Url and URLUpdater are ignored when Brokers is set
+	Retry         *RetryPolicy
+	EndPoint      string
+	DataSource    string // set to the queries without datasource
+	AuthToken     string // sent as cookie `skylight-aaa`, ignored when Authenticator is set
+	Authenticator Authenticator
+	Debug         bool // pretty print the requests, see QueryDebug and WithDebugInfo for the requests and responses
+	HttpClient    *http.Client
+	Logger        LoggerInterface
+	ResultCache   CacheAdapter
+	GroupByCache  GroupByCacheAdapter
+	SlotStrategy  SlotStrategy // slots of QueryGroupBy.CacheQuery, DefaultSlotStrategy when nil
+	Metrics       MetricsCollector
+	Tracer        Tracer // spans are not traced when nil
+	// MissingSegmentsAsError fail the queries with *MissingSegmentsError when druid reports missing segments,
+	// which means the result is partial.
+	MissingSegmentsAsError bool
+	// RequeryPartial re-issue QueryGroupBy(granularity `all`) and QueryTimeseries for the missing segments
+	// of partial results, and merge the results in. The result is complete when the requery misses no segment,
+	// uncovered intervals are not requeried as no segment covers them.
+	RequeryPartial bool
+	// Codec wire format of the native queries whose results are read as a whole, JSONCodec when nil
+	Codec Codec
+	// DefaultContext defaults of the query contexts of all queries, the keys set in the queries win.
+	// Its QueryID and SQLQueryID are ignored, every query gets its own id.
+	DefaultContext *QueryContext
+	// Gzip send `Accept-Encoding: gzip` and decompress gzip responses explicitly,
+	// for http clients whose transport does not do it transparently
+	Gzip bool
+
+	mu          sync.RWMutex
+	updatedURL  string
+	middlewares []Middleware
+	running     map[string]*runningQuery
 }
 
+var emptyLogger LoggerInterface = &EmptyLogger{}
+
+// Query query druid with the given query, the result is stored into query.
+// Use Do to leave the query untouched.
 func (c *Client) Query(query Query) (err error) {
-	query.setup()
+	return c.QueryContext(context.Background(), query)
+}
+
+// QueryContext query druid with the given query, the result is stored into query.
+// ctx's deadline is sent to druid as the `timeout` context key, and when ctx is done
+// the http request is aborted and the query is canceled on the broker.
+func (c *Client) QueryContext(ctx context.Context, query Query) error {
+	_, err := c.queryCall(ctx, query)
+	return err
+}
+
+// QueryMeta query druid like QueryContext, and return the metadata of the druid response.
+// The metadata is returned along with the error when druid responds with failure.
+func (c *Client) QueryMeta(ctx context.Context, query Query) (*ResponseMeta, error) {
+	call, err := c.queryCall(ctx, query)
+	return call.meta(), err
+}
+
+func (c *Client) queryCall(ctx context.Context, query Query) (call *Call, err error) {
+	query.Setup()
 	setDataSource(query, c.DataSource)
 	var reqJson []byte
 	if c.Debug {
@@ -114,128 +184,508 @@ func (c *Client) Query(query Query) (err error) {
 		return
 	}
 
-	var result []byte
-	needCache := (c.ResultCache != nil && query.shouldCache())
-	if needCache {
-		c.logger().Debugf("[%s] quering from cache...", "Client.Query")
-		qKey := dataKey(reqJson)
-		var cached bool
-		result, cached = c.ResultCache.Get(qKey)
-		c.logger().Debugf("[%s] is cache hit:%v", "Client.Query", cached)
-		if !cached || len(result) < CacheThresholdLower {
-			result, err = c.QueryRaw(reqJson)
-			if err != nil {
-				return
-			}
-			if len(result) >= CacheThresholdLower {
-				c.logger().Debugf("[%s] save query result to cache with key:%s", "Client.Query", qKey)
-				c.ResultCache.Set(qKey, result, 0)
-			}
-		}
-	} else {
-		result, err = c.QueryRaw(reqJson)
-		if err != nil {
-			return
-		}
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, SpanQuery, reqJson)
+	call = &Call{Query: query, Request: reqJson}
+	err = c.roundTrip(ctx, call, c.queryCached)
+	if err == nil {
+		_, decodeSpan := c.tracer().Start(ctx, SpanDecode)
+		err = query.OnResponse(call.Result)
+		decodeSpan.End(err)
 	}
-
-	return query.onResponse(result)
+	if err == nil && c.RequeryPartial && call.Meta.Partial() {
+		err = c.requery(ctx, call)
+	}
+	c.endSpan(span, call, err)
+	c.observeQuery(ctx, call, start, resultRows(query), err)
+	return
 }
 
 func (c *Client) logger() LoggerInterface {
 	if c.Logger == nil {
-		c.Logger = &EmptyLogger{}
+		return emptyLogger
 	}
 	return c.Logger
 }
 
 // QueryRaw raw query method
 func (c *Client) QueryRaw(req []byte) (result []byte, err error) {
+	return c.QueryRawContext(context.Background(), req)
+}
+
+// QueryRawContext raw query method with context, see QueryContext for ctx's effect.
+func (c *Client) QueryRawContext(ctx context.Context, req []byte) (result []byte, err error) {
+	result, _, err = c.QueryRawMeta(ctx, req)
+	return
+}
+
+// QueryRawMeta raw query method like QueryRawContext, and return the metadata of the druid response.
+func (c *Client) QueryRawMeta(ctx context.Context, req []byte) (result []byte, meta *ResponseMeta, err error) {
 	c.logger().Debugf("[%s] starting raw query...", "Client.QueryRaw")
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, SpanQuery, req)
+	call := &Call{Request: req}
+	err = c.roundTrip(ctx, call, c.sendCall)
+	c.endSpan(span, call, err)
+	c.observeQuery(ctx, call, start, 0, err)
+	if err != nil {
+		return nil, call.meta(), err
+	}
+
+	return call.Result, call.meta(), nil
+}
+
+// send post the query request to druid, the response with status 200 is returned with its body unread,
+// otherwise the druid error is decoded and returned along with the response whose body is closed.
+// The body of the returned response is a *countingBody.
+func (c *Client) send(ctx context.Context, api queryAPI, req []byte) (resp *http.Response, queryID string, err error) {
 	if c.HttpClient == nil {
 		err = fmt.Errorf("can not query when http client is nil")
 		return
 	}
-	if c.EndPoint == "" {
-		c.EndPoint = DefaultEndPoint
-	}
-	endPoint := c.EndPoint
-	if c.Debug {
+	endPoint := c.endPoint() + api.path
+	// the sql api does not support `pretty`
+	if c.Debug && api == nativeAPI {
 		endPoint += "?pretty"
-		c.LastRequest = string(req)
 	}
+	req, queryID, err = withQueryContext(ctx, req, api.idKey, c.defaultContext())
 	if err != nil {
 		return
 	}
+	c.debugRequest(ctx, req)
 
-	resp, err := c.queryRaw(endPoint, req)
+	runCtx, untrack := c.track(ctx, api, queryID, req)
+	resp, err = c.queryWithRetry(runCtx, endPoint, queryID, req)
 	if err != nil {
-		return result, err
+		// canceled before untracking, to send the cancel to the broker the query is sent to
+		c.cancelOnDone(ctx, api, queryID)
+		untrack()
+		return
+	}
+	resp.Body = &countingBody{ReadCloser: &trackedBody{ReadCloser: resp.Body, untrack: untrack}}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, rErr := ioutil.ReadAll(resp.Body)
+		if rErr != nil {
+			c.cancelOnDone(ctx, api, queryID)
+			return resp, queryID, rErr
+		}
+		c.debugResponse(ctx, resp.StatusCode, body)
+		return resp, queryID, newDruidError(resp, body)
 	}
 
-	result, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
+	return
+}
+
+// cancelOnDone cancel the query on druid broker when ctx is done before the query finished.
+func (c *Client) cancelOnDone(ctx context.Context, api queryAPI, queryID string) {
+	if ctx.Err() == nil {
 		return
 	}
-	if resp.Body != nil {
-		defer resp.Body.Close()
+	c.cancelDetached(api, queryID)
+}
+
+// cancelDetached cancel the query on druid broker, regardless of the query's context.
+func (c *Client) cancelDetached(api queryAPI, queryID string) {
+	if queryID == "" {
+		return
 	}
-	if c.Debug {
-		c.LastResponse = string(result)
+
+	cancelCtx, cancel := context.WithTimeout(context.Background(), CancelTimeout)
+	defer cancel()
+	if err := c.cancelQuery(cancelCtx, api, queryID); err != nil {
+		c.logger().Warnf("[%s] cancel query %s failed: %v", "Client.QueryRaw", queryID, err)
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: %s", resp.Status, string(result))
+// cancelQuery send `DELETE /druid/v2/{queryId}` or `DELETE /druid/v2/sql/{sqlQueryId}` to stop the query on druid broker.
+// The cancel is sent to the broker the query is sent to when the query is in flight of this client,
+// otherwise to all brokers of Brokers, for only the broker running the query can cancel it.
+func (c *Client) cancelQuery(ctx context.Context, api queryAPI, queryID string) error {
+	endPoint := c.endPoint() + api.path + "/" + url.PathEscape(queryID)
+
+	if baseURL := c.brokerOf(queryID); baseURL != "" {
+		resp, err := c.do(ctx, http.MethodDelete, baseURL, endPoint, nil)
+		return cancelResult(queryID, resp, err)
+	}
+	if c.Brokers == nil {
+		resp, err := c.request(ctx, http.MethodDelete, endPoint, nil, nil)
+		return cancelResult(queryID, resp, err)
 	}
 
-	return
+	var err error
+	canceled := false
+	for _, baseURL := range c.Brokers.URLs() {
+		resp, dErr := c.do(ctx, http.MethodDelete, baseURL, endPoint, nil)
+		if dErr = cancelResult(queryID, resp, dErr); dErr != nil {
+			err = dErr
+		} else {
+			canceled = true
+		}
+	}
+	if canceled {
+		return nil
+	}
+	return err
+}
+
+// cancelResult the error of the cancel request
+func cancelResult(queryID string, resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("cancel query %s: %s", queryID, resp.Status)
+	}
+	return nil
 }
 
-func (c *Client) queryRaw(endPoint string, req []byte) (*http.Response, error) {
+// request send the request to druid, dispatched is called with the base url of the druid node
+// before the request is sent to it when not nil.
+func (c *Client) request(ctx context.Context, method, endPoint string, req []byte, dispatched func(baseURL string)) (*http.Response, error) {
+	send := func(baseURL string) (*http.Response, error) {
+		if dispatched != nil {
+			dispatched(baseURL)
+		}
+		return c.do(ctx, method, baseURL, endPoint, req)
+	}
+	if c.Brokers != nil {
+		return c.Brokers.request(ctx, send)
+	}
+
+	baseURL := c.baseURL()
 	var urlUpdated bool
-	if c.Url == "" {
-		newBaseURL, uErr := c.URLUpdater()
+	if baseURL == "" {
+		newBaseURL, uErr := c.updateURL()
 		if uErr != nil {
 			return nil, uErr
 		}
-		c.Url = newBaseURL
+		baseURL = newBaseURL
 		urlUpdated = true
 	}
 
-	resp, err := queryRaw(c.HttpClient, c.Url, endPoint, c.AuthToken, req)
-	if err == nil || c.URLUpdater == nil || urlUpdated {
+	resp, err := send(baseURL)
+	if err == nil || c.URLUpdater == nil || urlUpdated || ctx.Err() != nil {
 		return resp, err
-	}	
+	}
 
 	needUpdateURL := regexp.MustCompile(URLUpdateErrReg).MatchString(err.Error())
 	if !needUpdateURL {
 		return nil, err
 	}
 
-	newBaseURL, uErr := c.URLUpdater()
+	newBaseURL, uErr := c.updateURL()
 	if uErr != nil {
 		return nil, uErr
 	}
-	c.Url = newBaseURL
-	return queryRaw(c.HttpClient, newBaseURL, endPoint, c.AuthToken, req)	
+	return send(newBaseURL)
+}
+
+func (c *Client) endPoint() string {
+	if c.EndPoint == "" {
+		return DefaultEndPoint
+	}
+	return c.EndPoint
+}
+
+// baseURL the url updated by URLUpdater, or Url when it never updated.
+func (c *Client) baseURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.updatedURL != "" {
+		return c.updatedURL
+	}
+	return c.Url
+}
+
+func (c *Client) updateURL() (string, error) {
+	if c.URLUpdater == nil {
+		return "", fmt.Errorf("can not query when url is empty and no url updater given")
+	}
+	newBaseURL, err := c.URLUpdater()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.updatedURL = newBaseURL
+	c.mu.Unlock()
+	return newBaseURL, nil
+}
+
+// do send the request to the druid node at baseURL, the request is sent again
+// after refreshing credentials when druid responds 401 and the authenticator can refresh.
+func (c *Client) do(ctx context.Context, method, baseURL, endPoint string, req []byte) (*http.Response, error) {
+	auth := c.authenticator()
+	resp, err := c.traceRaw(ctx, method, baseURL, endPoint, auth, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	refresher, ok := auth.(AuthRefresher)
+	if !ok {
+		return resp, err
+	}
+	if rErr := refresher.Refresh(ctx); rErr != nil {
+		c.logger().Warnf("[%s] refresh credentials failed: %v", "Client.QueryRaw", rErr)
+		return resp, err
+	}
+
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return c.traceRaw(ctx, method, baseURL, endPoint, auth, req)
 }
 
-func queryRaw(httpClient *http.Client, baseURL, endPoint, authToken string, req []byte) (*http.Response, error) {
-	request, err := http.NewRequest("POST", baseURL+endPoint, bytes.NewBuffer(req))
+func (c *Client) authenticator() Authenticator {
+	if c.Authenticator != nil {
+		return c.Authenticator
+	}
+	if c.AuthToken != "" {
+		return CookieAuth{Name: LegacyAuthCookieName, Value: c.AuthToken}
+	}
+	return nil
+}
+
+func queryRaw(ctx context.Context, httpClient *http.Client, method, baseURL, endPoint string, auth Authenticator, wire wireOptions, req []byte) (*http.Response, error) {
+	body, err := wire.encode(req)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(method, baseURL+endPoint, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	if authToken != "" {
-		cookie := &http.Cookie{
-			Name:  "skylight-aaa",
-			Value: authToken,
+	request = request.WithContext(ctx)
+	wire.setHeaders(request, req != nil)
+	if auth != nil {
+		if err := auth.Authenticate(request); err != nil {
+			return nil, err
 		}
-		request.AddCookie(cookie)
 	}
 
 	resp, err := httpClient.Do(request)
-	return resp, err
+	if err != nil {
+		return nil, err
+	}
+	if err := wire.decodeResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// withQueryContext reflect ctx and the default context keys into the request's druid query context.
+// ctx's deadline is set as `timeout` (milliseconds) when it is earlier than the given one,
+// and a random UUID is set as the query id(context key idKey) when no one is given,
+// the id is returned for tracking, canceling and retrying.
+// The request is returned as it is when there is nothing to set, or when it is not a json object,
+// otherwise only the context of it is rewritten and the rest is kept in its format.
+func withQueryContext(ctx context.Context, req []byte, idKey string, defaults map[string]interface{}) ([]byte, string, error) {
+	member, ok := findContextMember(req)
+	if !ok {
+		return req, "", nil
+	}
+	queryCtx := map[string]interface{}{}
+	if member.found {
+		decoder := json.NewDecoder(bytes.NewReader(req[member.start:member.end]))
+		decoder.UseNumber()
+		var given map[string]interface{}
+		if decoder.Decode(&given) == nil && given != nil {
+			queryCtx = given
+		}
+	}
+
+	changed := false
+	for k, v := range defaults {
+		if _, ok := queryCtx[k]; !ok {
+			queryCtx[k] = v
+			changed = true
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := int64(time.Until(deadline) / time.Millisecond)
+		if timeout < 1 {
+			timeout = 1
+		}
+		if old, ok := contextInt64(queryCtx, TIMEOUT); !ok || old <= 0 || old > timeout {
+			queryCtx[TIMEOUT] = timeout
+			changed = true
+		}
+	}
+	queryID, _ := queryCtx[idKey].(string)
+	if queryID == "" {
+		queryID = newQueryID()
+		queryCtx[idKey] = queryID
+		changed = true
+	}
+	if !changed {
+		return req, queryID, nil
+	}
+
+	newReq, err := member.replace(req, queryCtx)
+	return newReq, queryID, err
+}
+
+// contextMember location of the top-level `context` member in a serialized query
+type contextMember struct {
+	found bool
+	// start, end range of the context value when found
+	start, end int
+	// keyAt index of the context key when found
+	keyAt int
+	// closing index of the closing brace of the query
+	closing int
+	// empty whether the query has no member
+	empty bool
+}
+
+// findContextMember locate the `context` member of the json object req, ok is false when req is not a json object
+func findContextMember(req []byte) (m contextMember, ok bool) {
+	if !json.Valid(req) {
+		return m, false
+	}
+	i := skipJSONSpace(req, 0)
+	if req[i] != '{' {
+		return m, false
+	}
+	m.empty = true
+	for i = skipJSONSpace(req, i+1); req[i] != '}'; i = skipJSONSpace(req, i+1) {
+		if req[i] == ',' {
+			i = skipJSONSpace(req, i+1)
+		}
+		m.empty = false
+		keyAt := i
+		keyEnd := skipJSONValue(req, i)
+		var key string
+		json.Unmarshal(req[keyAt:keyEnd], &key)
+		valueAt := skipJSONSpace(req, skipJSONSpace(req, keyEnd)+1)
+		valueEnd := skipJSONValue(req, valueAt)
+		if key == "context" {
+			m.found, m.keyAt, m.start, m.end = true, keyAt, valueAt, valueEnd
+		}
+		i = skipJSONSpace(req, valueEnd) - 1
+	}
+	m.closing = i
+	return m, true
+}
+
+// replace set the value of the context member to queryCtx, the context is indented when req is
+func (m contextMember) replace(req []byte, queryCtx map[string]interface{}) ([]byte, error) {
+	indented := bytes.IndexByte(req, '\n') >= 0
+	anchor := m.closing
+	if m.found {
+		anchor = m.keyAt
+	}
+	prefix := lineIndent(req, anchor)
+	var value []byte
+	var err error
+	if indented {
+		if !m.found {
+			prefix += "  "
+		}
+		value, err = json.MarshalIndent(queryCtx, prefix, "  ")
+	} else {
+		value, err = json.Marshal(queryCtx)
+	}
+	if err != nil {
+		return req, err
+	}
+
+	var b bytes.Buffer
+	if m.found {
+		b.Write(req[:m.start])
+		b.Write(value)
+		b.Write(req[m.end:])
+		return b.Bytes(), nil
+	}
+	last := bytes.TrimRight(req[:m.closing], " \t\r\n")
+	b.Write(last)
+	if !m.empty {
+		b.WriteByte(',')
+	}
+	if indented {
+		b.WriteString("\n" + prefix + `"context": `)
+	} else {
+		b.WriteString(`"context":`)
+	}
+	b.Write(value)
+	b.Write(req[len(last):])
+	return b.Bytes(), nil
+}
+
+// lineIndent the leading spaces of the line containing data[i]
+func lineIndent(data []byte, i int) string {
+	start := bytes.LastIndexByte(data[:i], '\n') + 1
+	end := start
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[start:end])
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\r' || data[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// skipJSONValue the index after the json value starting at data[i], data must be valid json
+func skipJSONValue(data []byte, i int) int {
+	depth := 0
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			for i++; data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+			if depth < 0 {
+				return i
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+func contextInt64(queryCtx map[string]interface{}, key string) (int64, bool) {
+	switch v := queryCtx[key].(type) {
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// newQueryID generate a random(version 4) UUID for druid `queryId`
+func newQueryID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 // dataKey create a md5sum key for a given data
@@ -246,35 +696,15 @@ func dataKey(data []byte) string {
 	return fmt.Sprintf("%x", md5.Sum(sortedBytes))
 }
 
+// setDataSource set ds to the query unless the query has its own datasource
 func setDataSource(query Query, ds string) error {
-	switch query.(type) {
-	case *QueryGroupBy:
-		a := query.(*QueryGroupBy)
-		a.DataSource = ds
-	case *QueryScan:
-		a := query.(*QueryScan)
-		a.DataSource = ds
-	case *QuerySearch:
-		a := query.(*QuerySearch)
-		a.DataSource = ds
-	case *QuerySelect:
-		a := query.(*QuerySelect)
-		a.DataSource = ds
-	case *QuerySegmentMetadata:
-		a := query.(*QuerySegmentMetadata)
-		a.DataSource = ds
-	case *QueryTimeBoundary:
-		a := query.(*QueryTimeBoundary)
-		a.DataSource = ds
-	case *QueryTimeseries:
-		a := query.(*QueryTimeseries)
-		a.DataSource = ds
-	case *QueryTopN:
-		a := query.(*QueryTopN)
-		a.DataSource = ds
-	default:
+	setter, ok := query.(DataSourceSetter)
+	if !ok {
 		return fmt.Errorf("not support type: %v", query)
 	}
-
+	if getter, ok := query.(DataSourceGetter); ok && !isEmptyDataSource(getter.GetDataSource()) {
+		return nil
+	}
+	setter.SetDataSource(TableName(ds))
 	return nil
 }
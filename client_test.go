@@ -0,0 +1,170 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_withQueryContext(t *testing.T) {
+	deadlineCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cancelCtx, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		req         string
+		wantTimeout bool
+		wantQueryID string
+	}{
+		{"background", context.Background(), `{"queryType":"groupBy"}`, false, "*"},
+		{"cancel", cancelCtx, `{"queryType":"groupBy"}`, false, "*"},
+		{"deadline", deadlineCtx, `{"queryType":"groupBy"}`, true, "*"},
+		{"given queryId", deadlineCtx, `{"queryType":"groupBy","context":{"queryId":"abc"}}`, true, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, queryID, err := withQueryContext(tt.ctx, []byte(tt.req), QUERYID, nil)
+			if err != nil {
+				t.Fatalf("withQueryContext() error = %v", err)
+			}
+			if queryID == "" || (tt.wantQueryID != "*" && queryID != tt.wantQueryID) {
+				t.Errorf("withQueryContext() queryID = %s, want %s", queryID, tt.wantQueryID)
+			}
+
+			var query struct {
+				Context map[string]interface{} `json:"context"`
+			}
+			json.Unmarshal(got, &query)
+			if query.Context[QUERYID] != queryID {
+				t.Errorf("withQueryContext() context queryId = %v, want %v", query.Context[QUERYID], queryID)
+			}
+			timeout, ok := query.Context[TIMEOUT].(float64)
+			if ok != tt.wantTimeout || (ok && (timeout <= 0 || timeout > 60000)) {
+				t.Errorf("withQueryContext() context timeout = %v", query.Context[TIMEOUT])
+			}
+		})
+	}
+}
+
+func Test_withQueryContext_format(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      string
+		defaults map[string]interface{}
+		want     string
+	}{
+		{"not object", `not json`, nil, `not json`},
+		{"array", `[1]`, nil, `[1]`},
+		{"nothing to set", `{"b":1, "context":{"queryId":"abc"}}`, nil, `{"b":1, "context":{"queryId":"abc"}}`},
+		{"compact", `{"b":1,"a":{"x":[1,"}"]}}`, nil, `{"b":1,"a":{"x":[1,"}"]},"context":{"queryId":"abc"}}`},
+		{"empty", `{}`, nil, `{"context":{"queryId":"abc"}}`},
+		{"null context", `{"context":null,"b":1}`, nil, `{"context":{"queryId":"abc"},"b":1}`},
+		{
+			"indented",
+			"{\n  \"b\": 1,\n  \"a\": [\n    1\n  ]\n}",
+			nil,
+			"{\n  \"b\": 1,\n  \"a\": [\n    1\n  ],\n  \"context\": {\n    \"queryId\": \"abc\"\n  }\n}",
+		},
+		{
+			"indented context",
+			"{\n  \"context\": {\n    \"queryId\": \"abc\"\n  },\n  \"b\": 1\n}",
+			map[string]interface{}{"priority": 1},
+			"{\n  \"context\": {\n    \"priority\": 1,\n    \"queryId\": \"abc\"\n  },\n  \"b\": 1\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, queryID, err := withQueryContext(context.Background(), []byte(tt.req), QUERYID, tt.defaults)
+			if err != nil {
+				t.Fatalf("withQueryContext() error = %v", err)
+			}
+			want := tt.want
+			if queryID != "abc" {
+				want = strings.Replace(want, "abc", queryID, 1)
+			}
+			if string(got) != want {
+				t.Errorf("withQueryContext() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestClient_QueryContext_cancel(t *testing.T) {
+	deleted := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted <- strings.TrimPrefix(r.URL.Path, DefaultEndPoint+"/")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client()}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	query := &QueryTimeseries{
+		Granularity: GranAll,
+		Intervals:   []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"},
+		Context:     map[string]interface{}{QUERYID: "runaway"},
+	}
+
+	if err := client.QueryContext(ctx, query); err == nil {
+		t.Fatalf("Client.QueryContext() error = nil, want canceled")
+	}
+	select {
+	case id := <-deleted:
+		if id != "runaway" {
+			t.Errorf("Client.QueryContext() canceled query = %s, want runaway", id)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Client.QueryContext() did not cancel query on broker")
+	}
+}
+
+func TestClient_concurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Context map[string]interface{} `json:"context"`
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+		json.NewEncoder(w).Encode([]Timeseries{{Result: query.Context}})
+	}))
+	defer server.Close()
+
+	client := &Client{
+		URLUpdater: func() (string, error) { return server.URL, nil },
+		HttpClient: server.Client(),
+		Retry:      DefaultRetryPolicy(),
+		Debug:      true,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := &QueryTimeseries{Granularity: GranAll}
+			info, err := client.QueryDebug(context.Background(), query)
+			if err != nil {
+				t.Errorf("Client.QueryDebug() error = %v", err)
+				return
+			}
+			queryID := query.QueryResult[0].Result[QUERYID].(string)
+			if calls := info.Calls(); len(calls) != 1 || !strings.Contains(calls[0].Request, queryID) || !strings.Contains(calls[0].Response, queryID) {
+				t.Errorf("Client.QueryDebug() calls = %v, want request and response of query %s", calls, queryID)
+			}
+		}()
+	}
+	wg.Wait()
+}
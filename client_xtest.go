@@ -1,6 +1,7 @@
 package godruid
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -10,7 +11,7 @@ import (
 func TestGroupby(t *testing.T) {
 	Convey("TestGroupby", t, func() {
 		query := &QueryGroupBy{
-			DataSource:   "campaign",
+			DataSource:   TableName("campaign"),
 			Intervals:    []string{"2014-09-01T00:00/2020-01-01T00"},
 			Granularity:  GranAll,
 			Filter:       FilterAnd(FilterJavaScript("hour", "function(x) { return(x >= 1) }"), nil),
@@ -26,11 +27,11 @@ func TestGroupby(t *testing.T) {
 			Debug: true,
 		}
 
-		err := client.Query(query)
-		fmt.Println("requst", client.LastRequest)
+		info, err := client.QueryDebug(context.Background(), query)
+		fmt.Println("requst", info.LastRequest())
 		So(err, ShouldEqual, nil)
 
-		fmt.Println("response", client.LastResponse)
+		fmt.Println("response", info.LastResponse())
 
 		fmt.Printf("query.QueryResult:\n%v", query.QueryResult)
 
@@ -41,7 +42,7 @@ func TestSearch(t *testing.T) {
 	// return
 	Convey("TestSearch", t, func() {
 		query := &QuerySearch{
-			DataSource:       "campaign",
+			DataSource:       TableName("campaign"),
 			Intervals:        []string{"2014-09-01T00:00/2020-01-01T00"},
 			Granularity:      GranAll,
 			SearchDimensions: []string{"campaign_id", "hour"},
@@ -53,11 +54,11 @@ func TestSearch(t *testing.T) {
 			Debug: true,
 		}
 
-		err := client.Query(query)
+		info, err := client.QueryDebug(context.Background(), query)
 		So(err, ShouldEqual, nil)
 
-		fmt.Println("requst", client.LastRequest)
-		fmt.Println("response", client.LastResponse)
+		fmt.Println("requst", info.LastRequest())
+		fmt.Println("response", info.LastResponse())
 
 		fmt.Printf("query.QueryResult:\n%v", query.QueryResult)
 
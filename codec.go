@@ -0,0 +1,151 @@
+package godruid
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Media types of druid requests and responses
+const (
+	ContentTypeJSON  = "application/json"
+	ContentTypeSmile = "application/x-jackson-smile"
+)
+
+// Codec wire format of druid requests and responses.
+// Requests and results are JSON inside the client, codecs only convert them on the wire.
+// Only native queries whose results are read as a whole are sent in the codec,
+// sql queries, streaming scan queries and cancels are always sent in JSON.
+type Codec interface {
+	// ContentType media type of the format, sent as `Content-Type` and `Accept`
+	ContentType() string
+	// Encode convert the JSON request into the format
+	Encode(req []byte) ([]byte, error)
+	// Decode convert the response body of the format into JSON,
+	// the returned reader is closed with the response body if it is an io.Closer
+	Decode(body io.Reader) (io.Reader, error)
+}
+
+// JSONCodec the default codec
+type JSONCodec struct{}
+
+// ContentType implement Codec
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Encode implement Codec
+func (JSONCodec) Encode(req []byte) ([]byte, error) { return req, nil }
+
+// Decode implement Codec
+func (JSONCodec) Decode(body io.Reader) (io.Reader, error) { return body, nil }
+
+// SmileCodec druid's binary `application/x-jackson-smile` format.
+// The response is transcoded into JSON as it is read, it saves the bandwidth but not the JSON parsing.
+type SmileCodec struct{}
+
+// ContentType implement Codec
+func (SmileCodec) ContentType() string { return ContentTypeSmile }
+
+// Encode implement Codec
+func (SmileCodec) Encode(req []byte) ([]byte, error) { return jsonToSmile(req) }
+
+// Decode implement Codec, the returned reader is an io.Closer stopping the transcoding
+func (SmileCodec) Decode(body io.Reader) (io.Reader, error) {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(transcodeSmile(body, w))
+	}()
+	return r, nil
+}
+
+// wireOptions how requests are encoded and responses are decoded on the wire
+type wireOptions struct {
+	codec Codec
+	gzip  bool
+}
+
+// codecKey context key marking the request can be sent in Client.Codec
+type codecKey struct{}
+
+// withCodec mark the requests sent with ctx can be sent in Client.Codec
+func withCodec(ctx context.Context) context.Context {
+	return context.WithValue(ctx, codecKey{}, true)
+}
+
+// wire the wire options of the request sent with ctx, in JSON unless it is marked by withCodec
+func (c *Client) wire(ctx context.Context) wireOptions {
+	var codec Codec = JSONCodec{}
+	if enabled, _ := ctx.Value(codecKey{}).(bool); enabled && c.Codec != nil {
+		codec = c.Codec
+	}
+	return wireOptions{codec: codec, gzip: c.Gzip}
+}
+
+// encode the JSON request into the wire format
+func (w wireOptions) encode(req []byte) ([]byte, error) {
+	if req == nil {
+		return nil, nil
+	}
+	return w.codec.Encode(req)
+}
+
+// setHeaders set the headers of the wire format, `Content-Type` is set only when hasBody
+func (w wireOptions) setHeaders(request *http.Request, hasBody bool) {
+	request.Header.Set("Accept", w.codec.ContentType())
+	if w.gzip {
+		request.Header.Set("Accept-Encoding", "gzip")
+	}
+	if hasBody {
+		request.Header.Set("Content-Type", w.codec.ContentType())
+	}
+}
+
+// decodeResponse replace the response body with the decompressed and decoded one
+func (w wireOptions) decodeResponse(resp *http.Response) error {
+	body := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err == io.EOF {
+			gz, body = nil, bytes.NewReader(nil)
+		} else if err != nil {
+			return err
+		}
+		if gz != nil {
+			body = gz
+		}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == w.codec.ContentType() && mediaType != ContentTypeJSON {
+		decoded, err := w.codec.Decode(body)
+		if err != nil {
+			return err
+		}
+		body = decoded
+		resp.Header.Set("Content-Type", ContentTypeJSON)
+	}
+
+	if body != io.Reader(resp.Body) {
+		resp.Body = &decodedBody{Reader: body, closer: resp.Body}
+	}
+	return nil
+}
+
+// decodedBody decoded response body, closing the decoder if it is an io.Closer and the original body
+type decodedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	if decoder, ok := b.Reader.(io.Closer); ok {
+		decoder.Close()
+	}
+	return b.closer.Close()
+}
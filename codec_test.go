@@ -0,0 +1,179 @@
+package godruid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func assertJSONEqual(t *testing.T, got, want []byte) {
+	t.Helper()
+	var gotV, wantV interface{}
+	if err := json.Unmarshal(got, &gotV); err != nil {
+		t.Fatalf("invalid json %s: %v", got, err)
+	}
+	json.Unmarshal(want, &wantV)
+	if !reflect.DeepEqual(gotV, wantV) {
+		t.Errorf("json = %s, want %s", got, want)
+	}
+}
+
+func Test_smileToJSON(t *testing.T) {
+	got, err := smileToJSON(readFixture(t, "groupby_result.smile"))
+	if err != nil {
+		t.Fatalf("smileToJSON() error = %v", err)
+	}
+	assertJSONEqual(t, got, readFixture(t, "groupby_result.json"))
+
+	if _, err := smileToJSON([]byte(":)\n\x03\xf8\xfa\x80")); err == nil {
+		t.Errorf("smileToJSON() of truncated data error = nil")
+	}
+}
+
+func Test_jsonToSmile(t *testing.T) {
+	tests := []string{
+		string(readFixture(t, "groupby_result.json")),
+		`{"queryType":"groupBy","intervals":["2019-01-01/2019-01-02"],"limit":-2147483649,"big":-98765432109876543210987,"x":[1.5,-16,15,16,null,true,false,""]}`,
+	}
+	for _, tt := range tests {
+		smile, err := jsonToSmile([]byte(tt))
+		if err != nil {
+			t.Fatalf("jsonToSmile() error = %v", err)
+		}
+		got, err := smileToJSON(smile)
+		if err != nil {
+			t.Fatalf("smileToJSON() error = %v", err)
+		}
+		assertJSONEqual(t, got, []byte(tt))
+	}
+}
+
+func TestSmileCodec(t *testing.T) {
+	// expected bytes are written by the smile specification, not by the encoder under test
+	tests := []struct {
+		name  string
+		json  string
+		smile string
+	}{
+		{"tiny-int", `-1`, ":)\n\x00\xc1"},
+		{"int32", `100`, ":)\n\x00\x24\x03\x88"},
+		{"literals", `[true,false,null,""]`, ":)\n\x00\xf8\x23\x22\x21\x20\xf9"},
+		{"tiny-ascii", `"abc"`, ":)\n\x00\x42abc"},
+		{"object", `{"a":1,"bc":"d"}`, ":)\n\x00\xfa\x80a\xc2\x81bc\x40d\xfb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := SmileCodec{}
+			smile, err := codec.Encode([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("SmileCodec.Encode() error = %v", err)
+			}
+			if string(smile) != tt.smile {
+				t.Errorf("SmileCodec.Encode() = %q, want %q", smile, tt.smile)
+			}
+			decoded, err := codec.Decode(bytes.NewReader(smile))
+			if err != nil {
+				t.Fatalf("SmileCodec.Decode() error = %v", err)
+			}
+			got, err := ioutil.ReadAll(decoded)
+			if err != nil {
+				t.Fatalf("SmileCodec.Decode() read error = %v", err)
+			}
+			assertJSONEqual(t, got, []byte(tt.json))
+		})
+	}
+
+	decoded, _ := SmileCodec{}.Decode(bytes.NewReader([]byte(":)\n\x00\xf8\x23")))
+	if _, err := ioutil.ReadAll(decoded); err != errSmileTruncated {
+		t.Errorf("SmileCodec.Decode() of truncated data read error = %v, want %v", err, errSmileTruncated)
+	}
+}
+
+func TestClient_Codec(t *testing.T) {
+	fixture := readFixture(t, "groupby_result.smile.gz")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != ContentTypeSmile || r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("request headers = %v", r.Header)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := smileToJSON(body)
+		if err != nil {
+			t.Errorf("request is not smile: %v", err)
+		}
+		var query struct {
+			QueryType QueryType `json:"queryType"`
+		}
+		json.Unmarshal(req, &query)
+		if query.QueryType != GROUPBY {
+			t.Errorf("request = %s", req)
+		}
+
+		w.Header().Set("Content-Type", ContentTypeSmile)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), Codec: SmileCodec{}, Gzip: true}
+	query := &QueryGroupBy{Granularity: GranAll, Dimensions: []DimSpec{"os"}}
+	if err := client.QueryContext(context.Background(), query); err != nil {
+		t.Fatalf("Client.QueryContext() error = %v", err)
+	}
+
+	var want []GroupbyItem
+	json.Unmarshal(readFixture(t, "groupby_result.json"), &want)
+	if !reflect.DeepEqual(query.QueryResult, want) {
+		t.Errorf("Client.QueryContext() result = %v, want %v", query.QueryResult, want)
+	}
+}
+
+func TestClient_Codec_json(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if r.Header.Get("Accept") != ContentTypeJSON {
+			t.Errorf("%s %s Accept = %s, want %s", r.Method, r.URL.Path, r.Header.Get("Accept"), ContentTypeJSON)
+		}
+		if r.Method == http.MethodPost && r.Header.Get("Content-Type") != ContentTypeJSON {
+			t.Errorf("%s %s Content-Type = %s, want %s", r.Method, r.URL.Path, r.Header.Get("Content-Type"), ContentTypeJSON)
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == DefaultEndPoint+"/sql":
+			w.Write([]byte(`[{"cnt":3}]`))
+		default:
+			w.Write([]byte(`[{"segmentId":"s","columns":["a"],"events":[{"a":1}]}]`))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki", Codec: SmileCodec{}}
+	if _, err := client.QuerySQL(context.Background(), &SQLQuery{Query: "SELECT COUNT(*) AS cnt FROM wiki"}); err != nil {
+		t.Fatalf("Client.QuerySQL() error = %v", err)
+	}
+	it, err := client.Stream(context.Background(), &QueryScan{})
+	if err != nil {
+		t.Fatalf("Client.Stream() error = %v", err)
+	}
+	for it.Next() {
+	}
+	if err := it.Close(); err != nil {
+		t.Errorf("ScanIterator.Close() error = %v", err)
+	}
+	if err := client.Cancel("abc"); err != nil {
+		t.Errorf("Client.Cancel() error = %v", err)
+	}
+}
@@ -24,6 +24,10 @@ const (
 	ConditionOpMapInclude = "⊇"
 	// ConditionOpSetInclude set part include juge: for array, set
 	ConditionOpSetInclude = ConditionOpMapInclude
+	// ConditionOpIn in juge: the condition value is the array of the values to match
+	ConditionOpIn = "∈"
+	// ConditionOpNotIn not in juge
+	ConditionOpNotIn = "∉"
 )
 
 // Condition cache query condition
@@ -36,6 +40,13 @@ type Condition struct {
 
 // Match data?
 func (c *Condition) Match(data interface{}) bool {
+	switch c.Op {
+	case ConditionOpIn:
+		return c.matchIn(data)
+	case ConditionOpNotIn:
+		return !c.matchIn(data)
+	}
+
 	switch c.Value.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
 		return c.MatchNumber(data)
@@ -171,6 +182,22 @@ func (c Condition) matchMap(data, pattern map[string]interface{}) bool {
 	}
 }
 
+// matchIn whether data is any of the values of the condition value array
+func (c *Condition) matchIn(data interface{}) bool {
+	values := reflect.ValueOf(c.Value)
+	if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+		return false
+	}
+	eql := Condition{Op: ConditionOpEql}
+	for i := 0; i < values.Len(); i++ {
+		eql.Value = values.Index(i).Interface()
+		if eql.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
 func numberFloat64(x interface{}) float64 {
 	v := reflect.ValueOf(x)
 	switch x.(type) {
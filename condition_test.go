@@ -243,6 +243,14 @@ func TestCondition_Match(t *testing.T) {
 		{"other--<=--1", &Condition{Op: ConditionOpLET, Value: 1.1}, "0", false},
 		{"other--<=--1", &Condition{Op: ConditionOpLET, Value: 1.1}, "1", false},
 		{"other--<=--2", &Condition{Op: ConditionOpLET, Value: 1.1}, "2", false},
+
+		{"in--1", &Condition{Op: ConditionOpIn, Value: []interface{}{"a", 1}}, "a", true},
+		{"in--2", &Condition{Op: ConditionOpIn, Value: []interface{}{"a", 1}}, 1.0, true},
+		{"in--3", &Condition{Op: ConditionOpIn, Value: []interface{}{"a", 1}}, "b", false},
+		{"in--4", &Condition{Op: ConditionOpIn, Value: []string{"a", "b"}}, "b", true},
+		{"in--5", &Condition{Op: ConditionOpIn, Value: "a"}, "a", false},
+		{"notIn--1", &Condition{Op: ConditionOpNotIn, Value: []interface{}{"a", 1}}, "a", false},
+		{"notIn--2", &Condition{Op: ConditionOpNotIn, Value: []interface{}{"a", 1}}, 2, true},
 	}
 
 	for _, tt := range tests {
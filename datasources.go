@@ -0,0 +1,279 @@
+package godruid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DataSource datasource of a query: a TableName or one of the structured datasources
+type DataSource interface {
+	// dataSource mark the types which are druid datasources
+	dataSource()
+}
+
+// Join types of JoinDataSource
+const (
+	JoinInner = "INNER"
+	JoinLeft  = "LEFT"
+	JoinRight = "RIGHT"
+	JoinFull  = "FULL"
+)
+
+// TableName datasource of a table given by its name, serialized as the name string
+type TableName string
+
+type TableDataSource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type UnionDataSource struct {
+	Type        string   `json:"type"`
+	DataSources []string `json:"dataSources"`
+}
+
+type QueryDataSource struct {
+	Type  string `json:"type"`
+	Query Query  `json:"query"`
+}
+
+type InlineDataSource struct {
+	Type        string          `json:"type"`
+	ColumnNames []string        `json:"columnNames"`
+	ColumnTypes []string        `json:"columnTypes,omitempty"`
+	Rows        [][]interface{} `json:"rows"`
+}
+
+type LookupDataSource struct {
+	Type   string `json:"type"`
+	Lookup string `json:"lookup"`
+}
+
+type JoinDataSource struct {
+	Type        string     `json:"type"`
+	Left        DataSource `json:"left"`
+	Right       DataSource `json:"right"`
+	RightPrefix string     `json:"rightPrefix"`
+	Condition   string     `json:"condition"`
+	JoinType    string     `json:"joinType"`
+}
+
+func (TableName) dataSource()        {}
+func (TableDataSource) dataSource()  {}
+func (UnionDataSource) dataSource()  {}
+func (QueryDataSource) dataSource()  {}
+func (InlineDataSource) dataSource() {}
+func (LookupDataSource) dataSource() {}
+func (JoinDataSource) dataSource()   {}
+
+func DataSourceTable(name string) DataSource {
+	return &TableDataSource{
+		Type: "table",
+		Name: name,
+	}
+}
+
+func DataSourceUnion(dataSources ...string) DataSource {
+	return &UnionDataSource{
+		Type:        "union",
+		DataSources: dataSources,
+	}
+}
+
+// DataSourceQuery new subquery datasource of a copy of the query, the query is usually a QueryGroupBy
+func DataSourceQuery(query Query) DataSource {
+	subquery := cloneQuery(query)
+	subquery.Setup()
+	return &QueryDataSource{
+		Type:  "query",
+		Query: subquery,
+	}
+}
+
+// DataSourceInline new inline datasource of the rows, columnTypes is optional
+func DataSourceInline(columnNames []string, columnTypes []string, rows [][]interface{}) DataSource {
+	return &InlineDataSource{
+		Type:        "inline",
+		ColumnNames: columnNames,
+		ColumnTypes: columnTypes,
+		Rows:        rows,
+	}
+}
+
+func DataSourceLookup(lookup string) DataSource {
+	return &LookupDataSource{
+		Type:   "lookup",
+		Lookup: lookup,
+	}
+}
+
+// DataSourceJoin new join datasource, the condition is an expression referring to the right columns by rightPrefix,
+// such as `"country" == "r.k"`, see JoinEquals and JoinAnd
+func DataSourceJoin(left, right DataSource, rightPrefix, condition, joinType string) DataSource {
+	return &JoinDataSource{
+		Type:        "join",
+		Left:        left,
+		Right:       right,
+		RightPrefix: rightPrefix,
+		Condition:   condition,
+		JoinType:    joinType,
+	}
+}
+
+// JoinEquals join condition of the left column equal to the right column, the right column includes the right prefix
+func JoinEquals(leftColumn, rightColumn string) string {
+	return quoteIdentifier(leftColumn) + " == " + quoteIdentifier(rightColumn)
+}
+
+// JoinAnd join condition of all the conditions, at least one condition is required
+func JoinAnd(first string, rest ...string) string {
+	if len(rest) == 0 {
+		return first
+	}
+	parts := make([]string, 0, len(rest)+1)
+	for _, condition := range append([]string{first}, rest...) {
+		parts = append(parts, "("+condition+")")
+	}
+	return strings.Join(parts, " && ")
+}
+
+// quoteIdentifier quote the column name as an identifier of druid expressions
+func quoteIdentifier(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// decodeDataSource decode the JSON datasource into its DataSource type, subqueries are decoded by DecodeQuery
+func decodeDataSource(data []byte) (DataSource, error) {
+	var name *string
+	if err := json.Unmarshal(data, &name); err == nil {
+		if name == nil {
+			return nil, nil
+		}
+		return TableName(*name), nil
+	}
+
+	var typed struct {
+		Type  string          `json:"type"`
+		Query json.RawMessage `json:"query"`
+		Left  json.RawMessage `json:"left"`
+		Right json.RawMessage `json:"right"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+	var ds DataSource
+	switch typed.Type {
+	case "table":
+		ds = &TableDataSource{}
+	case "union":
+		ds = &UnionDataSource{}
+	case "inline":
+		ds = &InlineDataSource{}
+	case "lookup":
+		ds = &LookupDataSource{}
+	case "query":
+		query, err := DecodeQuery(typed.Query)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryDataSource{Type: typed.Type, Query: query}, nil
+	case "join":
+		left, err := decodeDataSource(typed.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeDataSource(typed.Right)
+		if err != nil {
+			return nil, err
+		}
+		var fields struct {
+			RightPrefix string `json:"rightPrefix"`
+			Condition   string `json:"condition"`
+			JoinType    string `json:"joinType"`
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		join := &JoinDataSource{
+			Type:        typed.Type,
+			Left:        left,
+			Right:       right,
+			RightPrefix: fields.RightPrefix,
+			Condition:   fields.Condition,
+			JoinType:    fields.JoinType,
+		}
+		return join, nil
+	default:
+		return nil, fmt.Errorf("not support datasource type: %q", typed.Type)
+	}
+	if err := json.Unmarshal(data, ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// isEmptyDataSource whether the datasource is not set
+func isEmptyDataSource(ds DataSource) bool {
+	return ds == nil || ds == TableName("")
+}
+
+// sameDataSource whether the datasources are the same, tables are compared by their names
+func sameDataSource(ds, ods DataSource) bool {
+	name, ok := tableName(ds)
+	oName, oOK := tableName(ods)
+	if ok || oOK {
+		return ok && oOK && name == oName
+	}
+	data, err := json.Marshal(ds)
+	oData, oErr := json.Marshal(ods)
+	return err == nil && oErr == nil && string(data) == string(oData)
+}
+
+// tableName the name of the table datasource, false for datasources not of a single table
+func tableName(ds DataSource) (string, bool) {
+	switch t := ds.(type) {
+	case TableName:
+		return string(t), true
+	case TableDataSource:
+		return t.Name, true
+	case *TableDataSource:
+		if t != nil {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+// dataSourceName the table name of the datasource, or its type for datasources not of a single table
+func dataSourceName(ds DataSource) string {
+	if name, ok := tableName(ds); ok {
+		return name
+	}
+	if ds == nil {
+		return ""
+	}
+	data, err := json.Marshal(ds)
+	if err != nil {
+		return ""
+	}
+	return dataSourceNameJSON(data)
+}
+
+// dataSourceNameJSON the same as dataSourceName for the serialized datasource
+func dataSourceNameJSON(data []byte) string {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		return name
+	}
+
+	var ds struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	json.Unmarshal(data, &ds)
+	if ds.Name != "" {
+		return ds.Name
+	}
+	return ds.Type
+}
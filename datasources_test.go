@@ -0,0 +1,139 @@
+package godruid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDataSource_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		ds   DataSource
+		want string
+	}{
+		{"name", TableName("wiki"), `"wiki"`},
+		{"table", DataSourceTable("wiki"), `{"type":"table","name":"wiki"}`},
+		{"union", DataSourceUnion("wiki1", "wiki2"), `{"type":"union","dataSources":["wiki1","wiki2"]}`},
+		{"lookup", DataSourceLookup("countries"), `{"type":"lookup","lookup":"countries"}`},
+		{
+			"inline",
+			DataSourceInline([]string{"k", "v"}, nil, [][]interface{}{{"a", 1}, {"b", 2}}),
+			`{"type":"inline","columnNames":["k","v"],"rows":[["a",1],["b",2]]}`,
+		},
+		{
+			"join",
+			DataSourceJoin(TableName("wiki"), DataSourceLookup("countries"), "r.", JoinEquals("country", "r.k"), JoinLeft),
+			`{"type":"join","left":"wiki","right":{"type":"lookup","lookup":"countries"},"rightPrefix":"r.","condition":"\"country\" == \"r.k\"","joinType":"LEFT"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.ds)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSourceQuery(t *testing.T) {
+	inner := &QueryGroupBy{DataSource: TableName("wiki"), Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+	data, err := json.Marshal(DataSourceQuery(inner))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var got struct {
+		Type  string `json:"type"`
+		Query struct {
+			QueryType  string `json:"queryType"`
+			DataSource string `json:"dataSource"`
+		} `json:"query"`
+	}
+	json.Unmarshal(data, &got)
+	if got.Type != "query" || got.Query.QueryType != "groupBy" || got.Query.DataSource != "wiki" {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+	if inner.QueryType != "" {
+		t.Errorf("DataSourceQuery() set up the given query: %v", inner.QueryType)
+	}
+}
+
+func TestClient_DataSource(t *testing.T) {
+	var req struct {
+		DataSource json.RawMessage `json:"dataSource"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	tests := []struct {
+		name string
+		ds   DataSource
+		want string
+	}{
+		{"none", nil, `"wiki"`},
+		{"empty", TableName(""), `"wiki"`},
+		{"name", TableName("wiki2"), `"wiki2"`},
+		{"table", DataSourceTable("wiki2"), `{"type":"table","name":"wiki2"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &QueryTimeseries{DataSource: tt.ds, Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+			if err := client.Query(query); err != nil {
+				t.Fatalf("Client.Query() error = %v", err)
+			}
+			var got, want interface{}
+			json.Unmarshal(req.DataSource, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("dataSource = %s, want %s", req.DataSource, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dataSourceName(t *testing.T) {
+	tests := []struct {
+		ds   DataSource
+		want string
+	}{
+		{TableName("wiki"), "wiki"},
+		{DataSourceTable("wiki"), "wiki"},
+		{DataSourceUnion("wiki1", "wiki2"), "union"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := dataSourceName(tt.ds); got != tt.want {
+			t.Errorf("dataSourceName(%v) = %q, want %q", tt.ds, got, tt.want)
+		}
+	}
+}
+
+func TestJoinAnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		want       string
+	}{
+		{"one", []string{JoinEquals("country", "r.k")}, `"country" == "r.k"`},
+		{"two", []string{JoinEquals("a", "r.a"), JoinEquals(`b"x`, `r.b\`)}, `("a" == "r.a") && ("b\"x" == "r.b\\")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JoinAnd(tt.conditions[0], tt.conditions[1:]...); got != tt.want {
+				t.Errorf("JoinAnd() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package godruid
+
+import (
+	"context"
+	"sync"
+)
+
+// DebugInfo per-call debug data, requests sent and responses received during a call.
+// Pass it to a call with WithDebugInfo, or use Client.QueryDebug.
+type DebugInfo struct {
+	mu    sync.Mutex
+	calls []DebugCall
+}
+
+// DebugCall a request sent to druid and its response
+type DebugCall struct {
+	Request    string
+	StatusCode int
+	Response   string
+}
+
+type debugInfoKey struct{}
+
+// WithDebugInfo return a context which records debug data of the calls made with it into info.
+func WithDebugInfo(ctx context.Context, info *DebugInfo) context.Context {
+	return context.WithValue(ctx, debugInfoKey{}, info)
+}
+
+func debugInfoFrom(ctx context.Context) *DebugInfo {
+	info, _ := ctx.Value(debugInfoKey{}).(*DebugInfo)
+	return info
+}
+
+// Calls recorded requests and responses in order
+func (d *DebugInfo) Calls() []DebugCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DebugCall{}, d.calls...)
+}
+
+// LastRequest the last recorded request
+func (d *DebugInfo) LastRequest() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.calls) == 0 {
+		return ""
+	}
+	return d.calls[len(d.calls)-1].Request
+}
+
+// LastResponse the last recorded response
+func (d *DebugInfo) LastResponse() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.calls) == 0 {
+		return ""
+	}
+	return d.calls[len(d.calls)-1].Response
+}
+
+func (d *DebugInfo) addRequest(req []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, DebugCall{Request: string(req)})
+}
+
+func (d *DebugInfo) setResponse(statusCode int, body []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.calls) == 0 {
+		return
+	}
+	d.calls[len(d.calls)-1].StatusCode = statusCode
+	d.calls[len(d.calls)-1].Response = string(body)
+}
+
+// QueryDebug query druid like QueryContext, and return the requests and responses of the call.
+func (c *Client) QueryDebug(ctx context.Context, query Query) (*DebugInfo, error) {
+	info := &DebugInfo{}
+	err := c.QueryContext(WithDebugInfo(ctx, info), query)
+	return info, err
+}
+
+// debugRequest record the request into the debug data of the call
+func (c *Client) debugRequest(ctx context.Context, req []byte) {
+	if info := debugInfoFrom(ctx); info != nil {
+		info.addRequest(req)
+	}
+}
+
+// debugResponse record the response body into the debug data of the call
+func (c *Client) debugResponse(ctx context.Context, statusCode int, body []byte) {
+	if info := debugInfoFrom(ctx); info != nil {
+		info.setResponse(statusCode, body)
+	}
+}
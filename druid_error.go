@@ -0,0 +1,166 @@
+package godruid
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Druid error codes, the `error` field of legacy druid error response
+const (
+	ErrCodeQueryTimeout          = "Query timeout"
+	ErrCodeQueryCapacityExceeded = "Query capacity exceeded"
+	ErrCodeQueryInterrupted      = "Query interrupted"
+	ErrCodeQueryCanceled         = "Query cancelled"
+	ErrCodeResourceLimitExceeded = "Resource limit exceeded"
+	ErrCodeUnsupportedOperation  = "Unsupported operation"
+	ErrCodeUnknownException      = "Unknown exception"
+)
+
+// Druid error categories, the `category` field of druid error response since druid 25
+const (
+	ErrCategoryDefensive        = "DEFENSIVE"
+	ErrCategoryInvalidInput     = "INVALID_INPUT"
+	ErrCategoryUnauthorized     = "UNAUTHORIZED"
+	ErrCategoryForbidden        = "FORBIDDEN"
+	ErrCategoryCapacityExceeded = "CAPACITY_EXCEEDED"
+	ErrCategoryCanceled         = "CANCELED"
+	ErrCategoryRuntimeFailure   = "RUNTIME_FAILURE"
+	ErrCategoryTimeout          = "TIMEOUT"
+	ErrCategoryUnsupported      = "UNSUPPORTED"
+	ErrCategoryNotFound         = "NOT_FOUND"
+	ErrCategoryUncategorized    = "UNCATEGORIZED"
+)
+
+// DruidError error returned by druid with a non-200 response.
+// Both the legacy format(error, errorMessage, errorClass, host)
+// and the newer format(error, errorCode, persona, category, errorMessage, context) are decoded.
+type DruidError struct {
+	StatusCode   int                    `json:"-"`
+	Status       string                 `json:"-"`
+	Code         string                 `json:"error"`
+	ErrorCode    string                 `json:"errorCode,omitempty"`
+	ErrorMessage string                 `json:"errorMessage,omitempty"`
+	ErrorClass   string                 `json:"errorClass,omitempty"`
+	Host         string                 `json:"host,omitempty"`
+	Category     string                 `json:"category,omitempty"`
+	Persona      string                 `json:"persona,omitempty"`
+	Context      map[string]interface{} `json:"context,omitempty"`
+	// Body raw response body
+	Body []byte `json:"-"`
+}
+
+// newDruidError decode druid error from the non-200 response and its body
+func newDruidError(resp *http.Response, body []byte) *DruidError {
+	e := &DruidError{}
+	if json.Unmarshal(body, e) != nil {
+		e = &DruidError{}
+	}
+	e.StatusCode = resp.StatusCode
+	e.Status = resp.Status
+	e.Body = body
+	return e
+}
+
+// readDruidError decode the druid error from the body of the failed response, the body is replaced to be read again
+func readDruidError(resp *http.Response) *DruidError {
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body = &decodedBody{Reader: bytes.NewReader(body), closer: resp.Body}
+	return newDruidError(resp, body)
+}
+
+// fromDruid whether the error is responded by druid itself, not by a gateway or proxy in front of druid
+func (e *DruidError) fromDruid() bool {
+	return e.Code != "" || e.ErrorCode != "" || e.ErrorClass != ""
+}
+
+func (e *DruidError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Code == "" && e.ErrorCode == "" && e.ErrorMessage == "" {
+		return fmt.Sprintf("%s: %s", status, string(e.Body))
+	}
+
+	parts := []string{}
+	for _, p := range []string{e.Code, e.ErrorCode, e.ErrorMessage} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	msg := fmt.Sprintf("%s: %s", status, strings.Join(parts, ": "))
+	if e.ErrorClass != "" {
+		msg += fmt.Sprintf(" (%s)", e.ErrorClass)
+	}
+	if e.Host != "" {
+		msg += fmt.Sprintf(" on %s", e.Host)
+	}
+	return msg
+}
+
+// Timeout whether the query timed out
+func (e *DruidError) Timeout() bool {
+	return e.Code == ErrCodeQueryTimeout || e.Category == ErrCategoryTimeout || e.StatusCode == http.StatusGatewayTimeout
+}
+
+// CapacityExceeded whether the query is rejected for the broker's query capacity
+func (e *DruidError) CapacityExceeded() bool {
+	return e.Code == ErrCodeQueryCapacityExceeded || e.Category == ErrCategoryCapacityExceeded || e.StatusCode == http.StatusTooManyRequests
+}
+
+// ResourceLimitExceeded whether the query exceeded a resource limit, such as merge buffers or groupBy rows limit
+func (e *DruidError) ResourceLimitExceeded() bool {
+	return e.Code == ErrCodeResourceLimitExceeded
+}
+
+// UnsupportedOperation whether the query uses an operation druid does not support
+func (e *DruidError) UnsupportedOperation() bool {
+	return e.Code == ErrCodeUnsupportedOperation || e.Category == ErrCategoryUnsupported
+}
+
+// Canceled whether the query is canceled on druid
+func (e *DruidError) Canceled() bool {
+	return e.Code == ErrCodeQueryCanceled || e.Code == ErrCodeQueryInterrupted || e.Category == ErrCategoryCanceled
+}
+
+// AsDruidError find the first *DruidError in err's chain
+func AsDruidError(err error) (*DruidError, bool) {
+	var e *DruidError
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// IsTimeout whether err is a druid query timeout error
+func IsTimeout(err error) bool {
+	e, ok := AsDruidError(err)
+	return ok && e.Timeout()
+}
+
+// IsCapacityExceeded whether err is a druid query capacity exceeded error
+func IsCapacityExceeded(err error) bool {
+	e, ok := AsDruidError(err)
+	return ok && e.CapacityExceeded()
+}
+
+// IsResourceLimitExceeded whether err is a druid resource limit exceeded error
+func IsResourceLimitExceeded(err error) bool {
+	e, ok := AsDruidError(err)
+	return ok && e.ResourceLimitExceeded()
+}
+
+// IsUnsupportedOperation whether err is a druid unsupported operation error
+func IsUnsupportedOperation(err error) bool {
+	e, ok := AsDruidError(err)
+	return ok && e.UnsupportedOperation()
+}
+
+// IsCanceled whether err is a druid query canceled error
+func IsCanceled(err error) bool {
+	e, ok := AsDruidError(err)
+	return ok && e.Canceled()
+}
@@ -0,0 +1,72 @@
+package godruid
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func Test_newDruidError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+		check       func(error) bool
+	}{
+		{
+			"legacy-timeout",
+			http.StatusGatewayTimeout,
+			`{"error":"Query timeout","errorMessage":"Timeout waiting for task.","errorClass":"org.apache.druid.query.QueryTimeoutException","host":"historical:8083"}`,
+			"504 Gateway Timeout: Query timeout: Timeout waiting for task. (org.apache.druid.query.QueryTimeoutException) on historical:8083",
+			IsTimeout,
+		},
+		{
+			"legacy-capacity",
+			http.StatusTooManyRequests,
+			`{"error":"Query capacity exceeded","errorMessage":"Total query capacity exceeded","errorClass":"org.apache.druid.query.QueryCapacityExceededException"}`,
+			"429 Too Many Requests: Query capacity exceeded: Total query capacity exceeded (org.apache.druid.query.QueryCapacityExceededException)",
+			IsCapacityExceeded,
+		},
+		{
+			"legacy-resource-limit",
+			http.StatusBadRequest,
+			`{"error":"Resource limit exceeded","errorMessage":"Not enough merge buffers"}`,
+			"400 Bad Request: Resource limit exceeded: Not enough merge buffers",
+			IsResourceLimitExceeded,
+		},
+		{
+			"new-unsupported",
+			http.StatusBadRequest,
+			`{"error":"druidException","errorCode":"general","persona":"USER","category":"UNSUPPORTED","errorMessage":"not supported","context":{}}`,
+			"400 Bad Request: druidException: general: not supported",
+			IsUnsupportedOperation,
+		},
+		{
+			"new-timeout",
+			http.StatusInternalServerError,
+			`{"error":"druidException","errorCode":"timeout","persona":"USER","category":"TIMEOUT","errorMessage":"Query did not complete within configured timeout period."}`,
+			"500 Internal Server Error: druidException: timeout: Query did not complete within configured timeout period.",
+			IsTimeout,
+		},
+		{
+			"not-json",
+			http.StatusBadGateway,
+			`<html>bad gateway</html>`,
+			"502 Bad Gateway: <html>bad gateway</html>",
+			func(err error) bool { _, ok := AsDruidError(err); return ok },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Status: fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status))}
+			err := fmt.Errorf("wrapped: %w", newDruidError(resp, []byte(tt.body)))
+			if got, _ := AsDruidError(err); got.Error() != tt.wantMessage {
+				t.Errorf("DruidError.Error() = %s, want %s", got.Error(), tt.wantMessage)
+			}
+			if !tt.check(err) {
+				t.Errorf("check(%v) = false, want true", err)
+			}
+		})
+	}
+}
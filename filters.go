@@ -1,25 +1,39 @@
 package godruid
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
 type Filter struct {
-	Type         string        `json:"type"`
-	Dimension    string        `json:"dimension,omitempty"`
-	Value        interface{}   `json:"value,omitempty"`
-	Values       interface{}   `json:"values,omitempty"`
-	Pattern      string        `json:"pattern,omitempty"`
-	Function     string        `json:"function,omitempty"`
-	Field        *Filter       `json:"field,omitempty"`
-	Fields       []*Filter     `json:"fields,omitempty"`
-	Upper        *float64      `json:"upper,omitempty"`
-	Lower        *float64      `json:"lower,omitempty"`
-	Ordering     Ordering      `json:"ordering,omitempty"`
-	UpperStrict  bool          `json:"upperStrict,omitempty"`
-	LowerStrict  bool          `json:"lowerStrict,omitempty"`
-	ExtractionFn ExtractionFn `json:"extractionFn,omitempty"`
-	Bound        *Bound        `json:"bound,omitempty"`
+	Type                  string       `json:"type"`
+	Dimension             string       `json:"dimension,omitempty"`
+	Dimensions            []DimSpec    `json:"dimensions,omitempty"`
+	Column                string       `json:"column,omitempty"`
+	Value                 interface{}  `json:"value,omitempty"`
+	Values                interface{}  `json:"values,omitempty"`
+	Pattern               string       `json:"pattern,omitempty"`
+	Function              string       `json:"function,omitempty"`
+	Expression            string       `json:"expression,omitempty"`
+	Query                 *SearchQuery `json:"query,omitempty"`
+	Intervals             Intervals    `json:"intervals,omitempty"`
+	Field                 *Filter      `json:"field,omitempty"`
+	Fields                []*Filter    `json:"fields,omitempty"`
+	MatchValueType        string       `json:"matchValueType,omitempty"`
+	MatchValue            interface{}  `json:"matchValue,omitempty"`
+	ElementMatchValueType string       `json:"elementMatchValueType,omitempty"`
+	ElementMatchValue     interface{}  `json:"elementMatchValue,omitempty"`
+	Upper                 *float64     `json:"upper,omitempty"`
+	Lower                 *float64     `json:"lower,omitempty"`
+	RangeUpper            interface{}  `json:"-"` // upper of the range filter, serialized as `upper`, unbounded when nil
+	RangeLower            interface{}  `json:"-"` // lower of the range filter, serialized as `lower`, unbounded when nil
+	Ordering              Ordering     `json:"ordering,omitempty"`
+	UpperStrict           bool         `json:"upperStrict,omitempty"`
+	LowerStrict           bool         `json:"lowerStrict,omitempty"`
+	UpperOpen             bool         `json:"upperOpen,omitempty"`
+	LowerOpen             bool         `json:"lowerOpen,omitempty"`
+	ExtractionFn          ExtractionFn `json:"extractionFn,omitempty"`
+	Bound                 *Bound       `json:"bound,omitempty"`
 }
 
 type Bound struct {
@@ -28,14 +42,27 @@ type Bound struct {
 	MaxCoords []float64 `json:"maxCoords,omitempty"`
 	Coords    []float64 `json:"coords,omitempty"`
 	Radius    float64   `json:"radius,omitempty"`
+	Abscissa  []float64 `json:"abscissa,omitempty"`
+	Ordinate  []float64 `json:"ordinate,omitempty"`
 }
 
 // ToConditions translate to Conditions for db query
 func (f Filter) ToConditions() ([]Condition, error) {
 	var result []Condition
+	if f.ExtractionFn != nil {
+		return result, fmt.Errorf("can not parse %s filter with extractionFn", f.Type)
+	}
 	switch f.Type {
 	case "selector":
 		result = append(result, Condition{FieldName: f.Dimension, Op: ConditionOpEql2, Value: f.Value})
+	case "in":
+		result = append(result, Condition{FieldName: f.Dimension, Op: ConditionOpIn, Value: f.Values})
+	case "equals":
+		result = append(result, Condition{FieldName: f.Column, Op: ConditionOpEql2, Value: f.MatchValue})
+	case "null":
+		result = append(result, Condition{FieldName: f.Column, Op: ConditionOpEql2, Value: nil})
+	case "true":
+		// matches all, no condition
 	case "not":
 		mirrorConditions, err := f.Field.ToConditions()
 		if err != nil {
@@ -44,6 +71,9 @@ func (f Filter) ToConditions() ([]Condition, error) {
 		if len(mirrorConditions) > 1 {
 			return result, fmt.Errorf("can not parse when using not logic whth complex filter(translated condition's length > 1)")
 		}
+		if len(mirrorConditions) == 0 {
+			return result, fmt.Errorf("can not parse when using not logic with filter matching all")
+		}
 		condition := mirrorConditions[0]
 		reverseMap := map[string]string{
 			ConditionOpEql:    ConditionOpNotEql,
@@ -53,6 +83,8 @@ func (f Filter) ToConditions() ([]Condition, error) {
 			ConditionOpLT:     ConditionOpGET,
 			ConditionOpLET:    ConditionOpGT,
 			ConditionOpNotEql: ConditionOpEql,
+			ConditionOpIn:     ConditionOpNotIn,
+			ConditionOpNotIn:  ConditionOpIn,
 		}
 		reverseOp, ok := reverseMap[condition.Op]
 		if !ok {
@@ -72,18 +104,17 @@ func (f Filter) ToConditions() ([]Condition, error) {
 		}
 	case "bound":
 		if f.Lower != nil {
-			condition := Condition{FieldName: f.Dimension, Value: *f.Lower, Op: ">="}
-			if f.LowerStrict {
-				condition.Op = ConditionOpGT
-			}
-			result = append(result, condition)
+			result = append(result, lowerCondition(f.Dimension, *f.Lower, f.LowerStrict))
 		}
 		if f.Upper != nil {
-			condition := Condition{FieldName: f.Dimension, Value: *f.Upper, Op: ConditionOpLET}
-			if f.UpperStrict {
-				condition.Op = ConditionOpLT
-			}
-			result = append(result, condition)
+			result = append(result, upperCondition(f.Dimension, *f.Upper, f.UpperStrict))
+		}
+	case "range":
+		if f.RangeLower != nil {
+			result = append(result, lowerCondition(f.Column, f.RangeLower, f.LowerOpen))
+		}
+		if f.RangeUpper != nil {
+			result = append(result, upperCondition(f.Column, f.RangeUpper, f.UpperOpen))
 		}
 	default:
 		return result, fmt.Errorf("not support filter type: %s", f.Type)
@@ -93,6 +124,60 @@ func (f Filter) ToConditions() ([]Condition, error) {
 	return result, nil
 }
 
+func lowerCondition(fieldName string, lower interface{}, strict bool) Condition {
+	condition := Condition{FieldName: fieldName, Value: lower, Op: ">="}
+	if strict {
+		condition.Op = ConditionOpGT
+	}
+	return condition
+}
+
+func upperCondition(fieldName string, upper interface{}, strict bool) Condition {
+	condition := Condition{FieldName: fieldName, Value: upper, Op: ConditionOpLET}
+	if strict {
+		condition.Op = ConditionOpLT
+	}
+	return condition
+}
+
+// filterJSON Filter without its JSON methods
+type filterJSON Filter
+
+// MarshalJSON serialize the bounds of the range filter as `lower` and `upper`
+func (f Filter) MarshalJSON() ([]byte, error) {
+	if f.Type != "range" {
+		return json.Marshal(filterJSON(f))
+	}
+	return json.Marshal(struct {
+		filterJSON
+		Upper interface{} `json:"upper,omitempty"`
+		Lower interface{} `json:"lower,omitempty"`
+	}{filterJSON(f), f.RangeUpper, f.RangeLower})
+}
+
+// UnmarshalJSON deserialize `lower` and `upper` of the range filter into RangeLower and RangeUpper
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	if typed.Type != "range" {
+		return json.Unmarshal(data, (*filterJSON)(f))
+	}
+	ranged := struct {
+		*filterJSON
+		Upper interface{} `json:"upper"`
+		Lower interface{} `json:"lower"`
+	}{filterJSON: (*filterJSON)(f)}
+	if err := json.Unmarshal(data, &ranged); err != nil {
+		return err
+	}
+	f.RangeUpper, f.RangeLower = ranged.Upper, ranged.Lower
+	return nil
+}
+
 type Ordering string
 
 const (
@@ -110,6 +195,17 @@ const (
 	UPPERLIMIT  = "upperLimit"
 )
 
+// Match value types of the equals, range and arrayContainsElement filters
+const (
+	MatchTypeString      = "STRING"
+	MatchTypeLong        = "LONG"
+	MatchTypeFloat       = "FLOAT"
+	MatchTypeDouble      = "DOUBLE"
+	MatchTypeStringArray = "ARRAY<STRING>"
+	MatchTypeLongArray   = "ARRAY<LONG>"
+	MatchTypeDoubleArray = "ARRAY<DOUBLE>"
+)
+
 type SpatialCoordinates struct {
 	Latitude  float64
 	Longitude float64
@@ -139,6 +235,20 @@ func FilterSpatialRadius(dimension string, coords SpatialCoordinates, radius flo
 	}
 }
 
+// FilterSpatialPolygon new spatial filter of the polygon with the points as its vertices
+func FilterSpatialPolygon(dimension string, points ...SpatialCoordinates) *Filter {
+	bound := &Bound{Type: "polygon"}
+	for _, p := range points {
+		bound.Abscissa = append(bound.Abscissa, p.Latitude)
+		bound.Ordinate = append(bound.Ordinate, p.Longitude)
+	}
+	return &Filter{
+		Type:      "spatial",
+		Dimension: dimension,
+		Bound:     bound,
+	}
+}
+
 func FilterSelector(dimension string, value interface{}) *Filter {
 	return &Filter{
 		Type:      "selector",
@@ -147,6 +257,112 @@ func FilterSelector(dimension string, value interface{}) *Filter {
 	}
 }
 
+func FilterSelectorExtraction(dimension string, value interface{}, fn ExtractionFn) *Filter {
+	return &Filter{
+		Type:         "selector",
+		Dimension:    dimension,
+		Value:        value,
+		ExtractionFn: fn,
+	}
+}
+
+func FilterIn(dimension string, values ...interface{}) *Filter {
+	return &Filter{
+		Type:      "in",
+		Dimension: dimension,
+		Values:    values,
+	}
+}
+
+func FilterInExtraction(dimension string, values []interface{}, fn ExtractionFn) *Filter {
+	return &Filter{
+		Type:         "in",
+		Dimension:    dimension,
+		Values:       values,
+		ExtractionFn: fn,
+	}
+}
+
+// FilterInterval new interval filter, the dimension is usually `__time`
+func FilterInterval(dimension string, intervals Intervals) *Filter {
+	return &Filter{
+		Type:      "interval",
+		Dimension: dimension,
+		Intervals: intervals,
+	}
+}
+
+func FilterColumnComparison(dimensions ...DimSpec) *Filter {
+	return &Filter{
+		Type:       "columnComparison",
+		Dimensions: dimensions,
+	}
+}
+
+func FilterSearch(dimension string, query *SearchQuery) *Filter {
+	return &Filter{
+		Type:      "search",
+		Dimension: dimension,
+		Query:     query,
+	}
+}
+
+func FilterExpression(expression string) *Filter {
+	return &Filter{
+		Type:       "expression",
+		Expression: expression,
+	}
+}
+
+func FilterTrue() *Filter {
+	return &Filter{Type: "true"}
+}
+
+func FilterFalse() *Filter {
+	return &Filter{Type: "false"}
+}
+
+func FilterNull(column string) *Filter {
+	return &Filter{
+		Type:   "null",
+		Column: column,
+	}
+}
+
+// FilterEquals new equals filter, matchValueType is one of the MatchType constants
+func FilterEquals(column string, matchValueType string, matchValue interface{}) *Filter {
+	return &Filter{
+		Type:           "equals",
+		Column:         column,
+		MatchValueType: matchValueType,
+		MatchValue:     matchValue,
+	}
+}
+
+// FilterRange new range filter, matchValueType is one of the MatchType constants,
+// lower or upper is unbounded when nil
+func FilterRange(column string, matchValueType string, lower interface{}, lowerOpen bool, upper interface{}, upperOpen bool) *Filter {
+	return &Filter{
+		Type:           "range",
+		Column:         column,
+		MatchValueType: matchValueType,
+		RangeLower:     lower,
+		LowerOpen:      lowerOpen,
+		RangeUpper:     upper,
+		UpperOpen:      upperOpen,
+	}
+}
+
+// FilterArrayContainsElement new arrayContainsElement filter, elementMatchValueType is one of the MatchType constants
+func FilterArrayContainsElement(column string, elementMatchValueType string, elementMatchValue interface{}) *Filter {
+	return &Filter{
+		Type:                  "arrayContainsElement",
+		Column:                column,
+		ElementMatchValueType: elementMatchValueType,
+		ElementMatchValue:     elementMatchValue,
+	}
+}
+
 func FilterUpperBound(dimension string, ordering Ordering, bound float64, strict bool) *Filter {
 	return &Filter{
 		Type:        "bound",
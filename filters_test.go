@@ -1,6 +1,7 @@
 package godruid
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -18,6 +19,12 @@ func TestFilter_ToConditions(t *testing.T) {
 		{"bound-lower-number-2", *FilterLowerBound("abc", "number", 123.0, true), []Condition{Condition{FieldName: "abc", Op: ConditionOpGT, Value: 123.0}}, false},
 		{"bound-upper-number-1", *FilterUpperBound("abc", "number", 123.0, false), []Condition{Condition{FieldName: "abc", Op: ConditionOpLET, Value: 123.0}}, false},
 		{"bound-upper-number-2", *FilterUpperBound("abc", "number", 123.0, true), []Condition{Condition{FieldName: "abc", Op: ConditionOpLT, Value: 123.0}}, false},
+		{
+			"bound-pointers",
+			Filter{Type: "bound", Dimension: "abc", Lower: float64Ptr(123), Upper: (*float64)(nil)},
+			[]Condition{Condition{FieldName: "abc", Op: ConditionOpGET, Value: 123.0}},
+			false,
+		},
 		{
 			"bound-lower-upper-1",
 			*FilterLowerUpperBound("abc", "number", 123.0, false, 456.0, false),
@@ -49,6 +56,22 @@ func TestFilter_ToConditions(t *testing.T) {
 			nil,
 			true,
 		},
+		{"in", *FilterIn("abc", "a", 1), []Condition{{FieldName: "abc", Op: ConditionOpIn, Value: []interface{}{"a", 1}}}, false},
+		{"not-in", *FilterNot(FilterIn("abc", "a")), []Condition{{FieldName: "abc", Op: ConditionOpNotIn, Value: []interface{}{"a"}}}, false},
+		{"equals", *FilterEquals("abc", MatchTypeLong, 1), []Condition{{FieldName: "abc", Op: ConditionOpEql2, Value: 1}}, false},
+		{"null", *FilterNull("abc"), []Condition{{FieldName: "abc", Op: ConditionOpEql2, Value: nil}}, false},
+		{"true", *FilterTrue(), nil, false},
+		{"not-true", *FilterNot(FilterTrue()), nil, true},
+		{"false", *FilterFalse(), nil, true},
+		{
+			"range",
+			*FilterRange("abc", MatchTypeString, "a", true, nil, false),
+			[]Condition{{FieldName: "abc", Op: ConditionOpGT, Value: "a"}},
+			false,
+		},
+		{"selector-extraction", *FilterSelectorExtraction("abc", "a", DimExFnRegex("(.*)", 1, false, "")), nil, true},
+		{"in-extraction", *FilterInExtraction("abc", []interface{}{"a"}, DimExFnRegex("(.*)", 1, false, "")), nil, true},
+		{"expression", *FilterExpression("abc > 1"), nil, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -63,3 +86,112 @@ func TestFilter_ToConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestFilter_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		f    *Filter
+		want string
+	}{
+		{"in", FilterIn("os", "ios", "android"), `{"type":"in","dimension":"os","values":["ios","android"]}`},
+		{
+			"in-extraction",
+			FilterInExtraction("os", []interface{}{"ios"}, &RegisteredLookupExtractionFn{Type: "registeredLookup", Lookup: "os"}),
+			`{"type":"in","dimension":"os","values":["ios"],"extractionFn":{"type":"registeredLookup","lookup":"os"}}`,
+		},
+		{
+			"selector-extraction",
+			FilterSelectorExtraction("os", "ios", &RegisteredLookupExtractionFn{Type: "registeredLookup", Lookup: "os"}),
+			`{"type":"selector","dimension":"os","value":"ios","extractionFn":{"type":"registeredLookup","lookup":"os"}}`,
+		},
+		{
+			"interval",
+			FilterInterval("__time", Intervals{"2020-01-01/P1D"}),
+			`{"type":"interval","dimension":"__time","intervals":["2020-01-01/P1D"]}`,
+		},
+		{"columnComparison", FilterColumnComparison("a", "b"), `{"type":"columnComparison","dimensions":["a","b"]}`},
+		{
+			"search",
+			FilterSearch("page", SearchQueryInsensitiveContains("druid")),
+			`{"type":"search","dimension":"page","query":{"type":"insensitive_contains","value":"druid"}}`,
+		},
+		{"expression", FilterExpression("a > b"), `{"type":"expression","expression":"a \u003e b"}`},
+		{"true", FilterTrue(), `{"type":"true"}`},
+		{"false", FilterFalse(), `{"type":"false"}`},
+		{"null", FilterNull("os"), `{"type":"null","column":"os"}`},
+		{"equals", FilterEquals("count", MatchTypeLong, 0), `{"type":"equals","column":"count","matchValueType":"LONG","matchValue":0}`},
+		{
+			"range",
+			FilterRange("page", MatchTypeString, "a", false, "m", true),
+			`{"type":"range","column":"page","matchValueType":"STRING","upperOpen":true,"upper":"m","lower":"a"}`,
+		},
+		{
+			"arrayContainsElement",
+			FilterArrayContainsElement("tags", MatchTypeString, "t1"),
+			`{"type":"arrayContainsElement","column":"tags","elementMatchValueType":"STRING","elementMatchValue":"t1"}`,
+		},
+		{
+			"bound",
+			FilterLowerBound("count", NUMERIC, 1, true),
+			`{"type":"bound","dimension":"count","lower":1,"ordering":"numeric","lowerStrict":true}`,
+		},
+		{
+			"polygon",
+			FilterSpatialPolygon("geo", SpatialCoordinates{1, 2}, SpatialCoordinates{3, 4}, SpatialCoordinates{5, 6}),
+			`{"type":"spatial","dimension":"geo","bound":{"type":"polygon","abscissa":[1,3,5],"ordinate":[2,4,6]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.f)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		want *Filter
+	}{
+		{"range", FilterRange("page", MatchTypeString, "a", false, "m", true)},
+		{"range-unbounded", FilterRange("count", MatchTypeDouble, 1.5, true, nil, false)},
+		{"bound", FilterLowerUpperBound("count", NUMERIC, 1, true, 10, false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			got := &Filter{}
+			if err := json.Unmarshal(data, got); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("json.Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestFilter_ToConditions_pointerBound(t *testing.T) {
+	lower := 10.0
+	f := Filter{Type: "bound", Dimension: "count", Lower: &lower, LowerStrict: true}
+	conditions, err := f.ToConditions()
+	if err != nil || len(conditions) != 1 {
+		t.Fatalf("Filter.ToConditions() = %v, %v", conditions, err)
+	}
+	if !conditions[0].Match(11.0) || conditions[0].Match(10.0) {
+		t.Errorf("Condition.Match() of pointer bound %v is wrong", conditions[0])
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/wuhuizuo/godruid
 
-go 1.12
+go 1.13
 
 require (
 	github.com/smartystreets/goconvey v0.0.0-20190306220146-200a235640ff
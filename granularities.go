@@ -7,11 +7,20 @@ type SimpleGran string
 const (
 	GranAll        SimpleGran = "all"
 	GranNone       SimpleGran = "none"
+	GranSecond     SimpleGran = "second"
 	GranMinute     SimpleGran = "minute"
+	GranFiveMin    SimpleGran = "five_minute"
+	GranTenMin     SimpleGran = "ten_minute"
 	GranFifteenMin SimpleGran = "fifteen_minute"
 	GranThirtyMin  SimpleGran = "thirty_minute"
 	GranHour       SimpleGran = "hour"
+	GranSixHour    SimpleGran = "six_hour"
+	GranEightHour  SimpleGran = "eight_hour"
 	GranDay        SimpleGran = "day"
+	GranWeek       SimpleGran = "week"
+	GranMonth      SimpleGran = "month"
+	GranQuarter    SimpleGran = "quarter"
+	GranYear       SimpleGran = "year"
 )
 
 type granDuration struct {
@@ -0,0 +1,344 @@
+package godruid
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntervalSet set of time ranges. The intervals of the set are kept sorted and non-empty,
+// the adjacent and overlapped ones are coalesced into one.
+type IntervalSet struct {
+	intervals []Interval
+}
+
+// NewIntervalSet new interval set of the intervals
+func NewIntervalSet(intervals ...Interval) IntervalSet {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, i := range intervals {
+		if !i.IsZero() {
+			sorted = append(sorted, i)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].start.Before(sorted[b].start) })
+
+	// coalesce in place, the written index never passes the read one
+	ret := sorted[:0]
+	for _, i := range sorted {
+		if n := len(ret); n > 0 && !i.start.After(ret[n-1].end) {
+			if i.end.After(ret[n-1].end) {
+				ret[n-1].end = i.end
+			}
+			continue
+		}
+		ret = append(ret, i)
+	}
+	return IntervalSet{intervals: ret}
+}
+
+// ParseIntervalSet parse the intervals into an interval set
+func ParseIntervalSet(intervals Intervals) (IntervalSet, error) {
+	parsed, err := intervals.Parse()
+	if err != nil {
+		return IntervalSet{}, err
+	}
+	return NewIntervalSet(parsed...), nil
+}
+
+// Intervals the sorted and coalesced intervals of the set
+func (s IntervalSet) Intervals() []Interval {
+	return append([]Interval(nil), s.intervals...)
+}
+
+// ToIntervals the intervals of the set in canonical format, to be set into queries
+func (s IntervalSet) ToIntervals() Intervals {
+	ret := make(Intervals, 0, len(s.intervals))
+	for _, i := range s.intervals {
+		ret = append(ret, i.String())
+	}
+	return ret
+}
+
+func (s IntervalSet) String() string {
+	return strings.Join(s.ToIntervals(), ",")
+}
+
+// IsEmpty whether the set covers no time
+func (s IntervalSet) IsEmpty() bool { return len(s.intervals) == 0 }
+
+// Duration total length of the intervals
+func (s IntervalSet) Duration() time.Duration {
+	var ret time.Duration
+	for _, i := range s.intervals {
+		ret += i.Duration()
+	}
+	return ret
+}
+
+// Union the set of the time covered by s or o
+func (s IntervalSet) Union(o IntervalSet) IntervalSet {
+	all := make([]Interval, 0, len(s.intervals)+len(o.intervals))
+	all = append(all, s.intervals...)
+	return NewIntervalSet(append(all, o.intervals...)...)
+}
+
+// Intersect the set of the time covered by both s and o
+func (s IntervalSet) Intersect(o IntervalSet) IntervalSet {
+	var ret []Interval
+	for a, b := 0, 0; a < len(s.intervals) && b < len(o.intervals); {
+		x, y := s.intervals[a], o.intervals[b]
+		start, end := laterTime(x.start, y.start), earlierTime(x.end, y.end)
+		if start.Before(end) {
+			ret = append(ret, NewInterval(start, end))
+		}
+		if x.end.Before(y.end) {
+			a++
+		} else {
+			b++
+		}
+	}
+	return NewIntervalSet(ret...)
+}
+
+// Subtract the set of the time covered by s but not o
+func (s IntervalSet) Subtract(o IntervalSet) IntervalSet {
+	var ret []Interval
+	b := 0
+	for _, x := range s.intervals {
+		start := x.start
+		for b < len(o.intervals) && !o.intervals[b].end.After(start) {
+			b++
+		}
+		for n := b; n < len(o.intervals) && o.intervals[n].start.Before(x.end); n++ {
+			if start.Before(o.intervals[n].start) {
+				ret = append(ret, NewInterval(start, o.intervals[n].start))
+			}
+			start = laterTime(start, o.intervals[n].end)
+		}
+		if start.Before(x.end) {
+			ret = append(ret, NewInterval(start, x.end))
+		}
+	}
+	return NewIntervalSet(ret...)
+}
+
+// Contains whether the time t is covered by the set
+func (s IntervalSet) Contains(t time.Time) bool {
+	n := sort.Search(len(s.intervals), func(n int) bool { return s.intervals[n].end.After(t) })
+	return n < len(s.intervals) && !s.intervals[n].start.After(t)
+}
+
+// Covers whether all the time of o is covered by the set
+func (s IntervalSet) Covers(o IntervalSet) bool {
+	return o.Subtract(s).IsEmpty()
+}
+
+// Overlaps whether any time of o is covered by the set
+func (s IntervalSet) Overlaps(o IntervalSet) bool {
+	return !s.Intersect(o).IsEmpty()
+}
+
+// Align extend the intervals outward to the bucket boundaries of the granularity,
+// the granularities `all` and `none` leave the set as it is.
+func (s IntervalSet) Align(gran Granlarity) (IntervalSet, error) {
+	b, err := newGranBucketer(gran)
+	if err != nil || b == nil {
+		return s, err
+	}
+	ret := make([]Interval, 0, len(s.intervals))
+	for _, i := range s.intervals {
+		start, end := b.floor(i.start), b.floor(i.end)
+		if end.Before(i.end) {
+			end = b.next(end)
+		}
+		ret = append(ret, NewInterval(start, end))
+	}
+	return NewIntervalSet(ret...), nil
+}
+
+// AlignInner shrink the intervals inward to the bucket boundaries of the granularity, so only the whole buckets are left.
+// The granularities `all` and `none` leave the set as it is.
+func (s IntervalSet) AlignInner(gran Granlarity) (IntervalSet, error) {
+	b, err := newGranBucketer(gran)
+	if err != nil || b == nil {
+		return s, err
+	}
+	ret := make([]Interval, 0, len(s.intervals))
+	for _, i := range s.intervals {
+		start := b.floor(i.start)
+		if start.Before(i.start) {
+			start = b.next(start)
+		}
+		ret = append(ret, NewInterval(start, b.floor(i.end)))
+	}
+	return NewIntervalSet(ret...), nil
+}
+
+func laterTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// granBucketer splits the time line into buckets of a period from an origin
+type granBucketer struct {
+	period Period
+	origin time.Time
+}
+
+// simpleGranPeriods periods of the simple granularities, buckets of them start from the epoch in UTC
+var simpleGranPeriods = map[SimpleGran]Period{
+	GranSecond:     {Seconds: 1},
+	GranMinute:     {Minutes: 1},
+	GranFiveMin:    {Minutes: 5},
+	GranTenMin:     {Minutes: 10},
+	GranFifteenMin: {Minutes: 15},
+	GranThirtyMin:  {Minutes: 30},
+	GranHour:       {Hours: 1},
+	GranSixHour:    {Hours: 6},
+	GranEightHour:  {Hours: 8},
+	GranDay:        {Days: 1},
+	GranWeek:       {Weeks: 1},
+	GranMonth:      {Months: 1},
+	GranQuarter:    {Months: 3},
+	GranYear:       {Years: 1},
+}
+
+// newGranBucketer bucketer of the granularity, nil for the granularities `all` and `none`
+func newGranBucketer(gran Granlarity) (*granBucketer, error) {
+	switch g := gran.(type) {
+	case string:
+		return newGranBucketer(SimpleGran(g))
+	case SimpleGran:
+		if g == GranAll || g == GranNone {
+			return nil, nil
+		}
+		period, ok := simpleGranPeriods[g]
+		if !ok {
+			return nil, fmt.Errorf("not support granularity: %s", g)
+		}
+		return newPeriodBucketer(period, time.UTC, time.Time{})
+	case granPeriod:
+		period, err := ParsePeriod(g.Period)
+		if err != nil {
+			return nil, err
+		}
+		loc := time.UTC
+		if g.TimeZone != "" {
+			if loc, err = time.LoadLocation(g.TimeZone); err != nil {
+				return nil, err
+			}
+		}
+		var origin time.Time
+		if g.Origin != "" {
+			if origin, err = ParseISOTime(g.Origin); err != nil {
+				return nil, err
+			}
+		}
+		return newPeriodBucketer(period, loc, origin)
+	case *granPeriod:
+		return newGranBucketer(*g)
+	case granDuration:
+		millis, err := strconv.ParseInt(g.Duration, 10, 64)
+		if err != nil || millis <= 0 {
+			return nil, fmt.Errorf("duration granularity(%s) is invalid", g.Duration)
+		}
+		var origin time.Time
+		if g.Origin != "" {
+			if origin, err = ParseISOTime(g.Origin); err != nil {
+				return nil, err
+			}
+		}
+		return newPeriodBucketer(Period{Millis: int(millis)}, time.UTC, origin)
+	case *granDuration:
+		return newGranBucketer(*g)
+	default:
+		return nil, fmt.Errorf("not support granularity: %v", gran)
+	}
+}
+
+// newPeriodBucketer bucketer of the period in loc. The default origin is the epoch in loc,
+// or the first monday after the epoch for periods of weeks.
+func newPeriodBucketer(period Period, loc *time.Location, origin time.Time) (*granBucketer, error) {
+	calendarMonths := period.Years != 0 || period.Months != 0
+	calendarDays := period.Weeks != 0 || period.Days != 0
+	clock := period.timeDuration() != 0
+	if calendarMonths && (calendarDays || clock) || calendarDays && clock {
+		return nil, fmt.Errorf("not support period of mixed units: %s", period)
+	}
+	if !calendarMonths && !calendarDays && !clock {
+		return nil, fmt.Errorf("not support empty period")
+	}
+
+	if origin.IsZero() {
+		origin = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+		if period.Weeks != 0 {
+			origin = time.Date(1970, 1, 5, 0, 0, 0, 0, loc)
+		}
+	}
+	return &granBucketer{period: period, origin: origin.In(loc)}, nil
+}
+
+// floor the start of the bucket containing t
+func (b *granBucketer) floor(t time.Time) time.Time {
+	t = t.In(b.origin.Location())
+	o := b.origin
+	var ret time.Time
+	switch p := b.period; {
+	case p.Years != 0 || p.Months != 0:
+		n := p.Years*12 + p.Months
+		diff := (t.Year()-o.Year())*12 + int(t.Month()-o.Month())
+		ret = addMonths(o, floorDiv(diff, n)*n)
+		if ret.After(t) {
+			ret = addMonths(o, (floorDiv(diff, n)-1)*n)
+		}
+	case p.Weeks != 0 || p.Days != 0:
+		n := p.Weeks*7 + p.Days
+		diff := int(civilDate(t).Sub(civilDate(o)) / (24 * time.Hour))
+		ret = o.AddDate(0, 0, floorDiv(diff, n)*n)
+		if ret.After(t) {
+			ret = o.AddDate(0, 0, (floorDiv(diff, n)-1)*n)
+		}
+	default:
+		// in milliseconds, time.Duration overflows for times centuries away from the origin
+		d := int64(p.timeDuration() / time.Millisecond)
+		diff := (t.Unix()-o.Unix())*1000 + int64(t.Nanosecond()/1e6-o.Nanosecond()/1e6)
+		ms := o.Unix()*1000 + int64(o.Nanosecond()/1e6) + floorDiv64(diff, d)*d
+		sec := floorDiv64(ms, 1000)
+		ret = time.Unix(sec, (ms-sec*1000)*1e6).In(o.Location())
+	}
+	return ret
+}
+
+// next the start of the bucket after the one starting at start
+func (b *granBucketer) next(start time.Time) time.Time {
+	return b.period.AddTo(start)
+}
+
+// civilDate the date of t as UTC midnight, to count calendar days
+func civilDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func floorDiv(a, b int) int {
+	return int(floorDiv64(int64(a), int64(b)))
+}
+
+func floorDiv64(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,124 @@
+package godruid
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustIntervalSet(t *testing.T, intervals ...string) IntervalSet {
+	t.Helper()
+	s, err := ParseIntervalSet(intervals)
+	if err != nil {
+		t.Fatalf("ParseIntervalSet() error = %v", err)
+	}
+	return s
+}
+
+func TestNewIntervalSet(t *testing.T) {
+	s := mustIntervalSet(t, "2020-01-03/P1D", "2020-01-01/P1D", "2020-01-02/P1D", "2020-01-10/P2D", "2020-01-11/P1D", "2020-01-20/2020-01-20")
+	want := Intervals{"2020-01-01T00:00:00.000Z/2020-01-04T00:00:00.000Z", "2020-01-10T00:00:00.000Z/2020-01-12T00:00:00.000Z"}
+	if got := s.ToIntervals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewIntervalSet() = %v, want %v", got, want)
+	}
+	if s.Duration() != 5*24*time.Hour {
+		t.Errorf("IntervalSet.Duration() = %v", s.Duration())
+	}
+}
+
+func TestIntervalSet_algebra(t *testing.T) {
+	a := mustIntervalSet(t, "2020-01-01/2020-01-05", "2020-01-10/2020-01-15")
+	b := mustIntervalSet(t, "2020-01-03/2020-01-12", "2020-01-14/2020-01-20")
+	tests := []struct {
+		name string
+		got  IntervalSet
+		want []string
+	}{
+		{"union", a.Union(b), []string{"2020-01-01/2020-01-20"}},
+		{"intersect", a.Intersect(b), []string{"2020-01-03/2020-01-05", "2020-01-10/2020-01-12", "2020-01-14/2020-01-15"}},
+		{"subtract", a.Subtract(b), []string{"2020-01-01/2020-01-03", "2020-01-12/2020-01-14"}},
+		{"subtract reverse", b.Subtract(a), []string{"2020-01-05/2020-01-10", "2020-01-15/2020-01-20"}},
+		{"subtract self", a.Subtract(a), nil},
+		{"intersect empty", a.Intersect(IntervalSet{}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := mustIntervalSet(t, tt.want...)
+			if !reflect.DeepEqual(tt.got.ToIntervals(), want.ToIntervals()) {
+				t.Errorf("got %v, want %v", tt.got, want)
+			}
+		})
+	}
+}
+
+func TestIntervalSet_Contains(t *testing.T) {
+	s := mustIntervalSet(t, "2020-01-01/2020-01-05", "2020-01-10/2020-01-15")
+	for _, tt := range []struct {
+		time string
+		want bool
+	}{
+		{"2019-12-31T23:59", false},
+		{"2020-01-01", true},
+		{"2020-01-04T23:59", true},
+		{"2020-01-05", false},
+		{"2020-01-12", true},
+		{"2020-01-15", false},
+	} {
+		tm, _ := ParseISOTime(tt.time)
+		if got := s.Contains(tm); got != tt.want {
+			t.Errorf("IntervalSet.Contains(%s) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+
+	if !s.Covers(mustIntervalSet(t, "2020-01-02/2020-01-03", "2020-01-10/2020-01-15")) {
+		t.Errorf("IntervalSet.Covers() = false, want true")
+	}
+	if s.Covers(mustIntervalSet(t, "2020-01-04/2020-01-11")) {
+		t.Errorf("IntervalSet.Covers() = true, want false")
+	}
+	if !s.Overlaps(mustIntervalSet(t, "2020-01-04/2020-01-11")) || s.Overlaps(mustIntervalSet(t, "2020-01-05/2020-01-10")) {
+		t.Errorf("IntervalSet.Overlaps() wrong")
+	}
+}
+
+func TestIntervalSet_Align(t *testing.T) {
+	s := mustIntervalSet(t, "2020-01-01T10:30/2020-01-03T01:00", "2020-03-15T00:00/2020-03-15T00:10")
+	tests := []struct {
+		name      string
+		gran      Granlarity
+		want      []string
+		wantInner []string
+	}{
+		{"all", GranAll, []string{"2020-01-01T10:30/2020-01-03T01:00", "2020-03-15T00:00/2020-03-15T00:10"}, []string{"2020-01-01T10:30/2020-01-03T01:00", "2020-03-15T00:00/2020-03-15T00:10"}},
+		{"hour", GranHour, []string{"2020-01-01T10/2020-01-03T01", "2020-03-15T00/2020-03-15T01"}, []string{"2020-01-01T11/2020-01-03T01"}},
+		{"day", GranDay, []string{"2020-01-01/2020-01-04", "2020-03-15/2020-03-16"}, []string{"2020-01-02/2020-01-03"}},
+		{"week", GranWeek, []string{"2019-12-30/2020-01-06", "2020-03-09/2020-03-16"}, nil},
+		{"month", GranMonth, []string{"2020-01-01/2020-02-01", "2020-03-01/2020-04-01"}, nil},
+		{"quarter", GranQuarter, []string{"2020-01-01/2020-04-01"}, nil},
+		{"year", "year", []string{"2020-01-01/2021-01-01"}, nil},
+		{"period", GranPeriod("P1D", "Asia/Shanghai", ""), []string{"2020-01-01T00:00+08:00/2020-01-04T00:00+08:00", "2020-03-15T00:00+08:00/2020-03-16T00:00+08:00"}, []string{"2020-01-02T00:00+08:00/2020-01-03T00:00+08:00"}},
+		{"duration", GranDuration("7200000", ""), []string{"2020-01-01T10/2020-01-03T02", "2020-03-15T00/2020-03-15T02"}, []string{"2020-01-01T12/2020-01-03T00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Align(tt.gran)
+			if err != nil {
+				t.Fatalf("IntervalSet.Align() error = %v", err)
+			}
+			if want := mustIntervalSet(t, tt.want...); !reflect.DeepEqual(got.ToIntervals(), want.ToIntervals()) {
+				t.Errorf("IntervalSet.Align() = %v, want %v", got, want)
+			}
+			got, err = s.AlignInner(tt.gran)
+			if err != nil {
+				t.Fatalf("IntervalSet.AlignInner() error = %v", err)
+			}
+			if want := mustIntervalSet(t, tt.wantInner...); !reflect.DeepEqual(got.ToIntervals(), want.ToIntervals()) {
+				t.Errorf("IntervalSet.AlignInner() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := s.Align(GranPeriod("P1DT1H", "", "")); err == nil {
+		t.Errorf("IntervalSet.Align() of mixed period error = nil")
+	}
+}
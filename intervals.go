@@ -1,3 +1,293 @@
 package godruid
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Intervals []string
+
+// IntervalsOf build Intervals of the Interval values, see ParseIntervals for interval strings
+func IntervalsOf(intervals ...Interval) Intervals {
+	ret := make(Intervals, 0, len(intervals))
+	for _, i := range intervals {
+		ret = append(ret, i.String())
+	}
+	return ret
+}
+
+// ParseIntervals build Intervals of the interval strings, which are checked by ParseISOInterval and kept as they are
+func ParseIntervals(intervals ...string) (Intervals, error) {
+	ret := make(Intervals, 0, len(intervals))
+	for _, s := range intervals {
+		if _, err := ParseISOInterval(s); err != nil {
+			return nil, err
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
+
+// Parse parse all the intervals by ParseISOInterval
+func (is Intervals) Parse() ([]Interval, error) {
+	ret := make([]Interval, 0, len(is))
+	for _, s := range is {
+		i, err := ParseISOInterval(s)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
+
+// intervalTimeLayout canonical layout of the interval endpoints, the same as druid's
+const intervalTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Interval time interval including its start and excluding its end
+type Interval struct {
+	start time.Time
+	end   time.Time
+}
+
+// NewInterval new interval of [start, end)
+func NewInterval(start, end time.Time) Interval {
+	return Interval{start: start, end: end}
+}
+
+// ParseISOInterval parse an ISO-8601 interval of the forms `<start>/<end>`, `<start>/<period>` and `<period>/<end>`,
+// such as `2014-09-01T00:00/2020-01-01T00`, `2020-01-01/P1M` and `P7D/2020-01-08`.
+// Datetimes without time zone are in UTC, the same as druid.
+func ParseISOInterval(s string) (Interval, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Interval{}, fmt.Errorf("interval(%s) format is invalid", s)
+	}
+
+	if strings.HasPrefix(parts[0], "P") {
+		period, err := ParsePeriod(parts[0])
+		if err != nil {
+			return Interval{}, err
+		}
+		end, err := ParseISOTime(parts[1])
+		if err != nil {
+			return Interval{}, err
+		}
+		return NewInterval(period.SubtractFrom(end), end), nil
+	}
+
+	start, err := ParseISOTime(parts[0])
+	if err != nil {
+		return Interval{}, err
+	}
+	if strings.HasPrefix(parts[1], "P") {
+		period, err := ParsePeriod(parts[1])
+		if err != nil {
+			return Interval{}, err
+		}
+		return NewInterval(start, period.AddTo(start)), nil
+	}
+	end, err := ParseISOTime(parts[1])
+	if err != nil {
+		return Interval{}, err
+	}
+	if end.Before(start) {
+		return Interval{}, fmt.Errorf("interval(%s) ends before its start", s)
+	}
+	return NewInterval(start, end), nil
+}
+
+// Start the start time of the interval, included
+func (i Interval) Start() time.Time { return i.start }
+
+// End the end time of the interval, excluded
+func (i Interval) End() time.Time { return i.end }
+
+// Duration length of the interval
+func (i Interval) Duration() time.Duration { return i.end.Sub(i.start) }
+
+// IsZero whether the interval is empty
+func (i Interval) IsZero() bool { return !i.end.After(i.start) }
+
+// String canonical format of the interval, such as `2020-01-01T00:00:00.000Z/2020-02-01T00:00:00.000Z`
+func (i Interval) String() string {
+	return i.start.Format(intervalTimeLayout) + "/" + i.end.Format(intervalTimeLayout)
+}
+
+// MarshalText implement encoding.TextMarshaler
+func (i Interval) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler
+func (i *Interval) UnmarshalText(text []byte) error {
+	parsed, err := ParseISOInterval(string(text))
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
+
+var isoTimeRegexp = regexp.MustCompile(`^(\d{4})(?:-?(\d{2})(?:-?(\d{2}))?)?` +
+	`(?:T(\d{2})(?::?(\d{2})(?::?(\d{2})(?:[.,](\d{1,9}))?)?)?)?` +
+	`(Z|[+-]\d{2}(?::?\d{2})?)?$`)
+
+// ParseISOTime parse an ISO-8601 datetime of any precision from year to nanosecond, in basic or extended format,
+// such as `2020`, `2020-01-01`, `2014-09-01T00:00`, `20200101T120000Z` and `2019-04-29T00:00:00.000+08:00`.
+// Datetimes without time zone are in UTC.
+func ParseISOTime(s string) (time.Time, error) {
+	m := isoTimeRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("datetime(%s) format is invalid", s)
+	}
+	field := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+	frac := field(m[7], "0")
+	frac += strings.Repeat("0", 9-len(frac))
+	zone := m[8]
+	switch {
+	case zone == "":
+		zone = "Z"
+	case len(zone) == 3:
+		zone += ":00"
+	case len(zone) == 5:
+		zone = zone[:3] + ":" + zone[3:]
+	}
+
+	// normalized to RFC3339, so that times of the local offset are in time.Local like time.Parse
+	normalized := fmt.Sprintf("%s-%s-%sT%s:%s:%s.%s%s",
+		m[1], field(m[2], "01"), field(m[3], "01"), field(m[4], "00"), field(m[5], "00"), field(m[6], "00"), frac, zone)
+	t, err := time.Parse(time.RFC3339Nano, normalized)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("datetime(%s) is invalid: %v", s, err)
+	}
+	return t, nil
+}
+
+// Period ISO-8601 period, the date part is added by calendar and the time part by duration
+type Period struct {
+	Years   int
+	Months  int
+	Weeks   int
+	Days    int
+	Hours   int
+	Minutes int
+	Seconds int
+	Millis  int
+}
+
+var periodRegexp = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?` +
+	`(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)(?:[.,](\d{1,3}))?S)?)?$`)
+
+// ParsePeriod parse an ISO-8601 period or duration, such as `P1M`, `P7D`, `PT1H` and `P1DT12H30M0.5S`
+func ParsePeriod(s string) (Period, error) {
+	m := periodRegexp.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return Period{}, fmt.Errorf("period(%s) format is invalid", s)
+	}
+	values := make([]int, len(m)-1)
+	for n, v := range m[1:] {
+		if v == "" {
+			continue
+		}
+		if n == len(values)-1 {
+			v += strings.Repeat("0", 3-len(v))
+		}
+		var err error
+		if values[n], err = strconv.Atoi(v); err != nil {
+			return Period{}, fmt.Errorf("period(%s) is invalid: %v", s, err)
+		}
+	}
+	return Period{
+		Years:   values[0],
+		Months:  values[1],
+		Weeks:   values[2],
+		Days:    values[3],
+		Hours:   values[4],
+		Minutes: values[5],
+		Seconds: values[6],
+		Millis:  values[7],
+	}, nil
+}
+
+// timeDuration the time part of the period
+func (p Period) timeDuration() time.Duration {
+	return time.Duration(p.Hours)*time.Hour + time.Duration(p.Minutes)*time.Minute +
+		time.Duration(p.Seconds)*time.Second + time.Duration(p.Millis)*time.Millisecond
+}
+
+// AddTo the time after the period from t, days are calendar days of t's location.
+// The day is clamped to the end of the month by years and months, `2020-01-31` plus `P1M` is `2020-02-29`.
+func (p Period) AddTo(t time.Time) time.Time {
+	return p.add(t, 1)
+}
+
+// SubtractFrom the time before the period from t, days are calendar days of t's location.
+// The day is clamped to the end of the month by years and months, `2020-03-31` minus `P1M` is `2020-02-29`.
+func (p Period) SubtractFrom(t time.Time) time.Time {
+	return p.add(t, -1)
+}
+
+// add the period sign times to t, field by field from years to millis the same as joda-time
+func (p Period) add(t time.Time, sign int) time.Time {
+	t = addMonths(t, sign*p.Years*12)
+	t = addMonths(t, sign*p.Months)
+	return t.AddDate(0, 0, sign*(p.Weeks*7+p.Days)).Add(time.Duration(sign) * p.timeDuration())
+}
+
+// addMonths add the months to t, the day is clamped to the last day of the target month
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	hour, min, sec := t.Clock()
+	return time.Date(first.Year(), first.Month(), d, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// String canonical format of the period, `PT0S` for the zero period
+func (p Period) String() string {
+	var b strings.Builder
+	b.WriteString("P")
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{p.Years, "Y"}, {p.Months, "M"}, {p.Weeks, "W"}, {p.Days, "D"}} {
+		if part.value != 0 {
+			b.WriteString(strconv.Itoa(part.value) + part.unit)
+		}
+	}
+	if p.Hours == 0 && p.Minutes == 0 && p.Seconds == 0 && p.Millis == 0 {
+		if b.Len() == 1 {
+			return "PT0S"
+		}
+		return b.String()
+	}
+	b.WriteString("T")
+	if p.Hours != 0 {
+		b.WriteString(strconv.Itoa(p.Hours) + "H")
+	}
+	if p.Minutes != 0 {
+		b.WriteString(strconv.Itoa(p.Minutes) + "M")
+	}
+	if p.Seconds != 0 || p.Millis != 0 {
+		b.WriteString(strconv.Itoa(p.Seconds))
+		if p.Millis != 0 {
+			b.WriteString(strings.TrimRight(fmt.Sprintf(".%03d", p.Millis), "0"))
+		}
+		b.WriteString("S")
+	}
+	return b.String()
+}
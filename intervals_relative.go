@@ -0,0 +1,106 @@
+package godruid
+
+import (
+	"fmt"
+	"time"
+)
+
+// nowFunc current time of the relative intervals, replaced in tests
+var nowFunc = time.Now
+
+// TimeUnit calendar unit of the relative intervals
+type TimeUnit int
+
+// Units of the relative intervals, aligned to the buckets of the same granularities in the time zone.
+// Weeks start from monday.
+const (
+	Minute TimeUnit = iota + 1
+	Hour
+	Day
+	Week
+	Month
+	Quarter
+	Year
+)
+
+var timeUnitGrans = map[TimeUnit]SimpleGran{
+	Minute:  GranMinute,
+	Hour:    GranHour,
+	Day:     GranDay,
+	Week:    GranWeek,
+	Month:   GranMonth,
+	Quarter: GranQuarter,
+	Year:    GranYear,
+}
+
+// Granularity the simple granularity of the unit
+func (u TimeUnit) Granularity() SimpleGran {
+	gran, ok := timeUnitGrans[u]
+	if !ok {
+		panic(fmt.Sprintf("invalid time unit: %d", u))
+	}
+	return gran
+}
+
+// bucketer bucketer of the unit in tz, nil tz is UTC
+func (u TimeUnit) bucketer(tz *time.Location) *granBucketer {
+	if tz == nil {
+		tz = time.UTC
+	}
+	b, err := newPeriodBucketer(simpleGranPeriods[u.Granularity()], tz, time.Time{})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Current the whole unit containing now in tz, such as today or this month
+func Current(unit TimeUnit, tz *time.Location) Interval {
+	b := unit.bucketer(tz)
+	start := b.floor(nowFunc())
+	return NewInterval(start, b.next(start))
+}
+
+// ToDate the unit containing now in tz until now, such as month to date
+func ToDate(unit TimeUnit, tz *time.Location) Interval {
+	b := unit.bucketer(tz)
+	now := nowFunc().In(b.origin.Location())
+	return NewInterval(b.floor(now), now)
+}
+
+// LastN the last n whole units before the current one in tz, such as the last 24 full hours
+func LastN(n int, unit TimeUnit, tz *time.Location) Interval {
+	b := unit.bucketer(tz)
+	end := b.floor(nowFunc())
+	start := end
+	for i := 0; i < n; i++ {
+		start = b.period.SubtractFrom(start)
+	}
+	return NewInterval(start, end)
+}
+
+// Today the whole day of now in tz
+func Today(tz *time.Location) Interval { return Current(Day, tz) }
+
+// Yesterday the whole day before today in tz
+func Yesterday(tz *time.Location) Interval { return LastN(1, Day, tz) }
+
+// ThisWeek the whole week of now in tz, from monday
+func ThisWeek(tz *time.Location) Interval { return Current(Week, tz) }
+
+// ThisMonth the whole month of now in tz
+func ThisMonth(tz *time.Location) Interval { return Current(Month, tz) }
+
+// WeekToDate the week of now in tz until now
+func WeekToDate(tz *time.Location) Interval { return ToDate(Week, tz) }
+
+// MonthToDate the month of now in tz until now
+func MonthToDate(tz *time.Location) Interval { return ToDate(Month, tz) }
+
+// YearToDate the year of now in tz until now
+func YearToDate(tz *time.Location) Interval { return ToDate(Year, tz) }
+
+// Trailing the period until now, calendar days of the period are counted in now's location
+func Trailing(period Period, now time.Time) Interval {
+	return NewInterval(period.SubtractFrom(now), now)
+}
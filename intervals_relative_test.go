@@ -0,0 +1,82 @@
+package godruid
+
+import (
+	"testing"
+	"time"
+)
+
+// withNow set the current time of the relative intervals, the returned func restores it
+func withNow(t *testing.T, now string) func() {
+	t.Helper()
+	tm, err := ParseISOTime(now)
+	if err != nil {
+		t.Fatalf("ParseISOTime() error = %v", err)
+	}
+	nowFunc = func() time.Time { return tm }
+	return func() { nowFunc = time.Now }
+}
+
+func TestRelativeIntervals(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer withNow(t, "2020-01-15T10:30:00+08:00")()
+	now := nowFunc().In(shanghai)
+	tests := []struct {
+		name string
+		got  Interval
+		want string
+	}{
+		{"last 24 hours", LastN(24, Hour, shanghai), "2020-01-14T10:00:00.000+08:00/2020-01-15T10:00:00.000+08:00"},
+		{"last 7 days", LastN(7, Day, shanghai), "2020-01-08T00:00:00.000+08:00/2020-01-15T00:00:00.000+08:00"},
+		{"last 2 months", LastN(2, Month, shanghai), "2019-11-01T00:00:00.000+08:00/2020-01-01T00:00:00.000+08:00"},
+		{"last day utc", LastN(1, Day, nil), "2020-01-14T00:00:00.000Z/2020-01-15T00:00:00.000Z"},
+		{"today", Today(shanghai), "2020-01-15T00:00:00.000+08:00/2020-01-16T00:00:00.000+08:00"},
+		{"yesterday", Yesterday(shanghai), "2020-01-14T00:00:00.000+08:00/2020-01-15T00:00:00.000+08:00"},
+		{"this week", ThisWeek(shanghai), "2020-01-13T00:00:00.000+08:00/2020-01-20T00:00:00.000+08:00"},
+		{"this month", ThisMonth(shanghai), "2020-01-01T00:00:00.000+08:00/2020-02-01T00:00:00.000+08:00"},
+		{"this quarter", Current(Quarter, shanghai), "2020-01-01T00:00:00.000+08:00/2020-04-01T00:00:00.000+08:00"},
+		{"week to date", WeekToDate(shanghai), "2020-01-13T00:00:00.000+08:00/2020-01-15T10:30:00.000+08:00"},
+		{"month to date", MonthToDate(shanghai), "2020-01-01T00:00:00.000+08:00/2020-01-15T10:30:00.000+08:00"},
+		{"year to date", YearToDate(shanghai), "2020-01-01T00:00:00.000+08:00/2020-01-15T10:30:00.000+08:00"},
+		{"trailing", Trailing(Period{Days: 7}, now), "2020-01-08T10:30:00.000+08:00/2020-01-15T10:30:00.000+08:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeIntervals_dst(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer withNow(t, "2020-03-09T10:00:00-04:00")()
+	if got, want := LastN(2, Day, ny), "2020-03-07T00:00:00.000-05:00/2020-03-09T00:00:00.000-04:00"; got.String() != want || got.Duration() != 47*time.Hour {
+		t.Errorf("LastN() = %s (%v), want %s", got, got.Duration(), want)
+	}
+	if got, want := Trailing(Period{Days: 1}, nowFunc().In(ny)), "2020-03-08T10:00:00.000-04:00/2020-03-09T10:00:00.000-04:00"; got.String() != want {
+		t.Errorf("Trailing() = %s, want %s", got, want)
+	}
+
+	defer withNow(t, "2020-03-08T04:30:00-04:00")()
+	if got, want := LastN(3, Hour, ny), "2020-03-08T00:00:00.000-05:00/2020-03-08T04:00:00.000-04:00"; got.String() != want || got.Duration() != 3*time.Hour {
+		t.Errorf("LastN() = %s (%v), want %s", got, got.Duration(), want)
+	}
+	if got, want := Today(ny), "2020-03-08T00:00:00.000-05:00/2020-03-09T00:00:00.000-04:00"; got.String() != want || got.Duration() != 23*time.Hour {
+		t.Errorf("Today() = %s (%v), want %s", got, got.Duration(), want)
+	}
+}
+
+func TestRelativeIntervals_query(t *testing.T) {
+	defer withNow(t, "2020-01-15T10:30:00Z")()
+	q := &QueryTimeseries{Intervals: IntervalsOf(LastN(7, Day, nil))}
+	if !q.ShouldCache() || q.Intervals[0] != "2020-01-08T00:00:00.000Z/2020-01-15T00:00:00.000Z" {
+		t.Errorf("QueryTimeseries.Intervals = %v", q.Intervals)
+	}
+}
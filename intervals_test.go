@@ -0,0 +1,135 @@
+package godruid
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseISOInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     string
+		wantErr  bool
+	}{
+		{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z", "2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z", false},
+		{"2014-09-01T00:00/2020-01-01T00", "2014-09-01T00:00:00.000Z/2020-01-01T00:00:00.000Z", false},
+		{"2020-01-01/P1M", "2020-01-01T00:00:00.000Z/2020-02-01T00:00:00.000Z", false},
+		{"P7D/2020-01-08", "2020-01-01T00:00:00.000Z/2020-01-08T00:00:00.000Z", false},
+		{"2020-01-31/P1M", "2020-01-31T00:00:00.000Z/2020-02-29T00:00:00.000Z", false},
+		{"2021-01-31T00:00:00Z/P1M", "2021-01-31T00:00:00.000Z/2021-02-28T00:00:00.000Z", false},
+		{"2020-02-29/P1Y", "2020-02-29T00:00:00.000Z/2021-02-28T00:00:00.000Z", false},
+		{"2020-02-29/P4Y", "2020-02-29T00:00:00.000Z/2024-02-29T00:00:00.000Z", false},
+		{"2020-01-31/P1M1D", "2020-01-31T00:00:00.000Z/2020-03-01T00:00:00.000Z", false},
+		{"2020-05-31/P1MT1H", "2020-05-31T00:00:00.000Z/2020-06-30T01:00:00.000Z", false},
+		{"P1M/2020-03-31", "2020-02-29T00:00:00.000Z/2020-03-31T00:00:00.000Z", false},
+		{"P1Y/2021-02-28", "2020-02-28T00:00:00.000Z/2021-02-28T00:00:00.000Z", false},
+		{"2020-01-01T12:00+08:00/PT1H30M", "2020-01-01T12:00:00.000+08:00/2020-01-01T13:30:00.000+08:00", false},
+		{"20200101T000000Z/P1W", "2020-01-01T00:00:00.000Z/2020-01-08T00:00:00.000Z", false},
+		{"2020-01-01T00:00:00.5-0500/PT0.5S", "2020-01-01T00:00:00.500-05:00/2020-01-01T00:00:01.000-05:00", false},
+		{"2020", "", true},
+		{"2020-01-02/2020-01-01", "", true},
+		{"2020-13-01/P1D", "", true},
+		{"P1D/P1D", "", true},
+		{"2020-01-01/PT", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			got, err := ParseISOInterval(tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseISOInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseISOInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseISOInterval_dst(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	start := time.Date(2020, 3, 8, 0, 0, 0, 0, ny)
+	i := NewInterval(start, Period{Days: 1}.AddTo(start))
+	if i.End() != time.Date(2020, 3, 9, 0, 0, 0, 0, ny) || i.Duration() != 23*time.Hour {
+		t.Errorf("interval = %s, duration %v", i, i.Duration())
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period  string
+		want    Period
+		wantErr bool
+	}{
+		{"P1M", Period{Months: 1}, false},
+		{"P7D", Period{Days: 7}, false},
+		{"PT1M", Period{Minutes: 1}, false},
+		{"P1Y2M3W4DT5H6M7.25S", Period{1, 2, 3, 4, 5, 6, 7, 250}, false},
+		{"P", Period{}, true},
+		{"P1DT", Period{}, true},
+		{"1D", Period{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			got, err := ParsePeriod(tt.period)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePeriod() = %+v, want %+v", got, tt.want)
+			}
+			if err == nil && got.String() != tt.period {
+				t.Errorf("Period.String() = %s, want %s", got, tt.period)
+			}
+		})
+	}
+	if s := (Period{}).String(); s != "PT0S" {
+		t.Errorf("Period{}.String() = %s", s)
+	}
+}
+
+func TestIntervalsOf(t *testing.T) {
+	i, _ := ParseISOInterval("2020-01-01/P1D")
+	j, _ := ParseISOInterval("P7D/2020-01-08")
+	intervals := IntervalsOf(i, j)
+	want := Intervals{"2020-01-01T00:00:00.000Z/2020-01-02T00:00:00.000Z", "2020-01-01T00:00:00.000Z/2020-01-08T00:00:00.000Z"}
+	if !reflect.DeepEqual(intervals, want) {
+		t.Errorf("IntervalsOf() = %v, want %v", intervals, want)
+	}
+
+	parsed, err := intervals.Parse()
+	if err != nil || len(parsed) != 2 || parsed[0].Start() != time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Intervals.Parse() = %v, %v", parsed, err)
+	}
+
+	var got struct {
+		Interval Interval `json:"interval"`
+	}
+	if err := json.Unmarshal([]byte(`{"interval":"2020-01-01/P1D"}`), &got); err != nil || got.Interval != i {
+		t.Errorf("json.Unmarshal() = %v, %v", got.Interval, err)
+	}
+}
+
+func TestParseIntervals(t *testing.T) {
+	intervals, err := ParseIntervals("P7D/2020-01-08", "2020-01-01/P1D")
+	if want := (Intervals{"P7D/2020-01-08", "2020-01-01/P1D"}); err != nil || !reflect.DeepEqual(intervals, want) {
+		t.Errorf("ParseIntervals() = %v, %v, want %v", intervals, err, want)
+	}
+	if _, err := ParseIntervals("2020-01-01/P1D", "2020"); err == nil {
+		t.Errorf("ParseIntervals() of invalid interval error = nil")
+	}
+}
+
+func TestDistributeIntervals_iso(t *testing.T) {
+	slots, err := DistributeIntervals("P1D/2020-01-08")
+	if err != nil || len(slots) != 1 || slots[0].TimeLen != 86400 {
+		t.Errorf("DistributeIntervals() = %v, %v", slots, err)
+	}
+	if !intervalShouldCache([]string{"2014-09-01T00:00/2020-01-01T00"}) || intervalShouldCache([]string{"2020-01-01/P1000Y"}) {
+		t.Errorf("intervalShouldCache() wrong for ISO intervals")
+	}
+}
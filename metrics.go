@@ -0,0 +1,325 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Query metric status
+const (
+	MetricStatusOK       = "ok"
+	MetricStatusError    = "error"
+	MetricStatusCanceled = "canceled"
+)
+
+// CacheStatus status of the ResultCache lookup for a query
+type CacheStatus string
+
+const (
+	// CacheSkip ResultCache is not used for the query
+	CacheSkip CacheStatus = ""
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+)
+
+// QueryMetric metric of a query call
+type QueryMetric struct {
+	QueryType  string
+	DataSource string
+	// Status one of MetricStatusOK, MetricStatusError and MetricStatusCanceled
+	Status string
+	// StatusCode http status code of druid response, 0 when no response received
+	StatusCode int
+	Latency    time.Duration
+	// BytesReceived bytes of the response body read, after decompression. 0 for results loaded from ResultCache
+	BytesReceived int64
+	// Rows count of result rows, 0 for raw queries
+	Rows  int
+	Cache CacheStatus
+}
+
+// SlotMetric metric of a GroupByCache slot lookup in QueryGroupBy.CacheQuery
+type SlotMetric struct {
+	DataSource string
+	Target     string
+	Hit        bool
+	Rows       int
+}
+
+// MetricsCollector collector of query metrics
+type MetricsCollector interface {
+	ObserveQuery(m QueryMetric)
+	ObserveSlot(m SlotMetric)
+}
+
+// observeQuery report the metric of the finished call to c.Metrics
+func (c *Client) observeQuery(ctx context.Context, call *Call, start time.Time, rows int, err error) {
+	if c.Metrics == nil {
+		return
+	}
+
+	m := QueryMetric{Status: MetricStatusOK, Latency: time.Since(start), Rows: rows}
+	if call.SQL != nil {
+		m.QueryType = "sql"
+	} else {
+		m.QueryType, m.DataSource = requestInfo(call.Request)
+	}
+	if call.Response != nil {
+		m.StatusCode = call.Response.StatusCode
+	}
+	if call.received != nil {
+		m.BytesReceived = call.received.count()
+	}
+	switch {
+	case call.Cached:
+		m.Cache = CacheHit
+	case call.cacheChecked:
+		m.Cache = CacheMiss
+	}
+	if err != nil {
+		m.Status = MetricStatusError
+		if ctx.Err() != nil {
+			m.Status = MetricStatusCanceled
+		}
+	}
+	c.Metrics.ObserveQuery(m)
+}
+
+// countingBody response body counting the bytes read, for streamed and failed responses as well
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+func (b *countingBody) count() int64 { return atomic.LoadInt64(&b.n) }
+
+// requestInfo query type and datasource name of the serialized native query
+func requestInfo(req []byte) (queryType, dataSource string) {
+	var info struct {
+		QueryType  string          `json:"queryType"`
+		DataSource json.RawMessage `json:"dataSource"`
+	}
+	if json.Unmarshal(req, &info) != nil {
+		return
+	}
+	return info.QueryType, dataSourceNameJSON(info.DataSource)
+}
+
+// resultRows count of the result rows stored in query
+func resultRows(query Query) int {
+	switch q := query.(type) {
+	case *QueryGroupBy:
+		return len(q.QueryResult)
+	case *QueryScan:
+		rows := 0
+		for _, blob := range q.QueryResult {
+			rows += len(blob.Events)
+		}
+		return rows
+	case *QuerySearch:
+		return len(q.QueryResult)
+	case *QuerySelect:
+		return len(q.QueryResult.Result.Events)
+	case *QuerySegmentMetadata:
+		return len(q.QueryResult)
+	case *QueryTimeBoundary:
+		return len(q.QueryResult)
+	case *QueryDataSourceMetadata:
+		return len(q.QueryResult)
+	case *QueryTimeseries:
+		return len(q.QueryResult)
+	case *QueryTopN:
+		rows := 0
+		for _, item := range q.QueryResult {
+			rows += len(item.Result)
+		}
+		return rows
+	default:
+		return 0
+	}
+}
+
+// DefaultLatencyBuckets default upper bounds(seconds) of query latency histograms
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// DefaultBytesBuckets default upper bounds of received bytes histograms
+var DefaultBytesBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20}
+
+// Histogram non-cumulative histogram, Counts[i] is the count of values <= Bounds[i]
+// and greater than Bounds[i-1], the last one of Counts is for values greater than all bounds.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Count  uint64    `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+// NewHistogram new histogram with the upper bounds
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe add a value into the histogram
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += v
+}
+
+func (h *Histogram) clone() *Histogram {
+	ret := *h
+	ret.Counts = append([]uint64{}, h.Counts...)
+	return &ret
+}
+
+// QueryStats aggregated metrics of queries with the same type, datasource and status
+type QueryStats struct {
+	QueryType   string     `json:"queryType"`
+	DataSource  string     `json:"dataSource"`
+	Status      string     `json:"status"`
+	Count       uint64     `json:"count"`
+	Rows        uint64     `json:"rows"`
+	CacheHits   uint64     `json:"cacheHits"`
+	CacheMisses uint64     `json:"cacheMisses"`
+	Latency     *Histogram `json:"latency"`
+	Bytes       *Histogram `json:"bytes"`
+}
+
+// SlotStats aggregated metrics of GroupByCache slots with the same datasource and target
+type SlotStats struct {
+	DataSource string `json:"dataSource"`
+	Target     string `json:"target"`
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Rows       uint64 `json:"rows"`
+}
+
+// MetricsSnapshot exported metrics of MemoryMetrics
+type MetricsSnapshot struct {
+	Queries []QueryStats `json:"queries"`
+	Slots   []SlotStats  `json:"slots"`
+}
+
+// MemoryMetrics in-memory metrics collector, safe for concurrent use.
+type MemoryMetrics struct {
+	LatencyBuckets []float64
+	BytesBuckets   []float64
+
+	mu      sync.Mutex
+	queries map[[3]string]*QueryStats
+	slots   map[[2]string]*SlotStats
+}
+
+// NewMemoryMetrics new in-memory metrics collector with default buckets
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{LatencyBuckets: DefaultLatencyBuckets, BytesBuckets: DefaultBytesBuckets}
+}
+
+// ObserveQuery implement MetricsCollector
+func (m *MemoryMetrics) ObserveQuery(qm QueryMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queries == nil {
+		m.queries = map[[3]string]*QueryStats{}
+	}
+
+	key := [3]string{qm.QueryType, qm.DataSource, qm.Status}
+	stats, ok := m.queries[key]
+	if !ok {
+		stats = &QueryStats{
+			QueryType:  qm.QueryType,
+			DataSource: qm.DataSource,
+			Status:     qm.Status,
+			Latency:    NewHistogram(m.LatencyBuckets),
+			Bytes:      NewHistogram(m.BytesBuckets),
+		}
+		m.queries[key] = stats
+	}
+	stats.Count++
+	stats.Rows += uint64(qm.Rows)
+	switch qm.Cache {
+	case CacheHit:
+		stats.CacheHits++
+	case CacheMiss:
+		stats.CacheMisses++
+	}
+	stats.Latency.Observe(qm.Latency.Seconds())
+	stats.Bytes.Observe(float64(qm.BytesReceived))
+}
+
+// ObserveSlot implement MetricsCollector
+func (m *MemoryMetrics) ObserveSlot(sm SlotMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.slots == nil {
+		m.slots = map[[2]string]*SlotStats{}
+	}
+
+	key := [2]string{sm.DataSource, sm.Target}
+	stats, ok := m.slots[key]
+	if !ok {
+		stats = &SlotStats{DataSource: sm.DataSource, Target: sm.Target}
+		m.slots[key] = stats
+	}
+	if sm.Hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+	stats.Rows += uint64(sm.Rows)
+}
+
+// Snapshot copy of the metrics collected so far, sorted by keys
+func (m *MemoryMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := MetricsSnapshot{Queries: []QueryStats{}, Slots: []SlotStats{}}
+	for _, stats := range m.queries {
+		s := *stats
+		s.Latency = stats.Latency.clone()
+		s.Bytes = stats.Bytes.clone()
+		snapshot.Queries = append(snapshot.Queries, s)
+	}
+	for _, stats := range m.slots {
+		snapshot.Slots = append(snapshot.Slots, *stats)
+	}
+	sort.Slice(snapshot.Queries, func(i, j int) bool {
+		a, b := snapshot.Queries[i], snapshot.Queries[j]
+		if a.QueryType != b.QueryType {
+			return a.QueryType < b.QueryType
+		}
+		if a.DataSource != b.DataSource {
+			return a.DataSource < b.DataSource
+		}
+		return a.Status < b.Status
+	})
+	sort.Slice(snapshot.Slots, func(i, j int) bool {
+		a, b := snapshot.Slots[i], snapshot.Slots[j]
+		if a.DataSource != b.DataSource {
+			return a.DataSource < b.DataSource
+		}
+		return a.Target < b.Target
+	})
+	return snapshot
+}
+
+// Reset drop all collected metrics
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = nil
+	m.slots = nil
+}
@@ -0,0 +1,120 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testResultCache map[string][]byte
+
+func (c testResultCache) Get(key string) ([]byte, bool) {
+	data, ok := c[key]
+	return data, ok
+}
+
+func (c testResultCache) Set(key string, data []byte, lifespan time.Duration) {
+	c[key] = data
+}
+
+func (c testResultCache) Release(key string) {
+	delete(c, key)
+}
+
+func TestHistogram_Observe(t *testing.T) {
+	h := NewHistogram([]float64{1, 10})
+	for _, v := range []float64{0.5, 1, 5, 10, 11} {
+		h.Observe(v)
+	}
+	if want := []uint64{2, 2, 1}; !reflect.DeepEqual(h.Counts, want) {
+		t.Errorf("Histogram.Counts = %v, want %v", h.Counts, want)
+	}
+	if h.Count != 5 || h.Sum != 27.5 {
+		t.Errorf("Histogram count, sum = %d, %v, want 5, 27.5", h.Count, h.Sum)
+	}
+}
+
+func TestClient_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}},{"timestamp":"2019-01-02T00:00:00.000Z","result":{"count":2}}]`))
+	}))
+	defer server.Close()
+
+	metrics := NewMemoryMetrics()
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki", ResultCache: testResultCache{}, Metrics: metrics}
+	for i := 0; i < 2; i++ {
+		query := &QueryTimeseries{Granularity: GranDay, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-03T00:00:00Z"}}
+		if err := client.Query(query); err != nil {
+			t.Fatalf("Client.Query() error = %v", err)
+		}
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot.Queries) != 1 {
+		t.Fatalf("MemoryMetrics.Snapshot() queries = %v, want 1 group", snapshot.Queries)
+	}
+	stats := snapshot.Queries[0]
+	if stats.QueryType != "timeseries" || stats.DataSource != "wiki" || stats.Status != MetricStatusOK {
+		t.Errorf("QueryStats key = %s, %s, %s", stats.QueryType, stats.DataSource, stats.Status)
+	}
+	if stats.Count != 2 || stats.Rows != 4 || stats.CacheHits != 1 || stats.CacheMisses != 1 {
+		t.Errorf("QueryStats = %+v, want 2 queries, 4 rows, 1 hit and 1 miss", stats)
+	}
+	if stats.Latency.Count != 2 || stats.Bytes.Counts[0] != 2 {
+		t.Errorf("QueryStats histograms = %+v, %+v", stats.Latency, stats.Bytes)
+	}
+
+	metrics.Reset()
+	if snapshot := metrics.Snapshot(); len(snapshot.Queries) != 0 {
+		t.Errorf("MemoryMetrics.Reset() left %v", snapshot.Queries)
+	}
+}
+
+type testMetrics struct {
+	queries []QueryMetric
+}
+
+func (m *testMetrics) ObserveQuery(qm QueryMetric) { m.queries = append(m.queries, qm) }
+func (m *testMetrics) ObserveSlot(sm SlotMetric)   {}
+
+func TestClient_Metrics_bytesReceived(t *testing.T) {
+	errBody := `{"error":"Query timeout"}`
+	scanBody := `[{"segmentId":"s1","columns":["a"],"events":[{"a":1}]}]`
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls > 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(errBody))
+			return
+		}
+		w.Write([]byte(scanBody))
+	}))
+	defer server.Close()
+
+	metrics := &testMetrics{}
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki", Metrics: metrics}
+	it, err := client.Stream(context.Background(), &QueryScan{})
+	if err != nil {
+		t.Fatalf("Client.Stream() error = %v", err)
+	}
+	for it.Next() {
+	}
+	it.Close()
+
+	if _, err := client.QueryRaw([]byte(`{}`)); err == nil {
+		t.Fatalf("Client.QueryRaw() error = nil, want druid error")
+	}
+
+	if len(metrics.queries) != 2 {
+		t.Fatalf("observed queries = %v, want 2", metrics.queries)
+	}
+	if got := metrics.queries[0].BytesReceived; got != int64(len(scanBody)) {
+		t.Errorf("streamed BytesReceived = %d, want %d", got, len(scanBody))
+	}
+	if got := metrics.queries[1].BytesReceived; got != int64(len(errBody)) {
+		t.Errorf("error BytesReceived = %d, want %d", got, len(errBody))
+	}
+}
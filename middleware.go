@@ -0,0 +1,155 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// Call a druid query call passing through the middleware chain.
+type Call struct {
+	// Query the typed native query, nil for raw and sql queries
+	Query Query
+	// SQL the sql query, nil for native queries
+	SQL *SQLQuery
+	// Request the serialized request, middlewares may rewrite it before calling next
+	Request []byte
+	// QueryID id of the query sent to druid, set after the request is sent
+	QueryID string
+	// Response the http response with its body read into Result, set after the request is sent.
+	// The body of streaming scan queries and sql queries is left unread for the caller.
+	Response *http.Response
+	// Result the response body, nil for streaming scan queries and sql queries
+	Result []byte
+	// Cached whether the Result is loaded from Client.ResultCache
+	Cached bool
+	// Meta metadata of the druid response, set after the response is received
+	Meta *ResponseMeta
+
+	cacheChecked bool
+	received     *countingBody
+}
+
+// Encode serialize the typed query(Query or SQL) into Request again, call it after rewriting the query.
+func (call *Call) Encode() (err error) {
+	switch {
+	case call.Query != nil:
+		call.Request, err = json.Marshal(call.Query)
+	case call.SQL != nil:
+		call.Request, err = json.Marshal(call.SQL)
+	default:
+		err = errors.New("no typed query to encode")
+	}
+	return
+}
+
+// RoundTrip execute a call, the error returned is a *DruidError when druid responds with failure.
+type RoundTrip func(ctx context.Context, call *Call) error
+
+// Middleware wrap the next RoundTrip, to inspect or rewrite calls before and after they are executed.
+type Middleware func(next RoundTrip) RoundTrip
+
+// Use append middlewares to the chain, the first used middleware is the outermost one.
+// Middlewares of typed native queries also wrap the lookup of ResultCache.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// roundTrip execute the call through the middleware chain ended with terminal
+func (c *Client) roundTrip(ctx context.Context, call *Call, terminal RoundTrip) error {
+	c.mu.RLock()
+	middlewares := c.middlewares
+	c.mu.RUnlock()
+
+	next := terminal
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+	return next(ctx, call)
+}
+
+// queryCached RoundTrip of typed native queries, results are loaded from and saved to ResultCache when possible.
+func (c *Client) queryCached(ctx context.Context, call *Call) error {
+	if c.ResultCache == nil || call.Query == nil || !call.Query.ShouldCache() {
+		return c.sendCall(ctx, call)
+	}
+
+	c.logger().Debugf("[%s] quering from cache...", "Client.Query")
+	call.cacheChecked = true
+	qKey := c.cacheKey(call.Request)
+	_, span := c.tracer().Start(ctx, SpanCacheLookup)
+	result, cached := c.ResultCache.Get(qKey)
+	span.SetAttribute("cache.hit", cached)
+	span.End(nil)
+	c.logger().Debugf("[%s] is cache hit:%v", "Client.Query", cached)
+	if cached && len(result) >= CacheThresholdLower {
+		call.Result = result
+		call.Cached = true
+		call.Meta = &ResponseMeta{Cached: true}
+		return nil
+	}
+
+	if err := c.sendCall(ctx, call); err != nil {
+		return err
+	}
+	c.saveResult(call, call.Result)
+	return nil
+}
+
+// saveResult save the result of the call into ResultCache, partial results are never cached
+func (c *Client) saveResult(call *Call, result []byte) {
+	if len(result) < CacheThresholdLower || call.Meta.Partial() {
+		return
+	}
+	qKey := c.cacheKey(call.Request)
+	c.logger().Debugf("[%s] save query result to cache with key:%s", "Client.Query", qKey)
+	c.ResultCache.Set(qKey, result, 0)
+}
+
+// sendCall RoundTrip sending the call to druid and reading the whole response body,
+// native queries are sent in Client.Codec.
+func (c *Client) sendCall(ctx context.Context, call *Call) error {
+	api := nativeAPI
+	if call.SQL != nil {
+		api = sqlAPI
+	} else {
+		ctx = withCodec(ctx)
+	}
+	if err := c.openCall(ctx, call); err != nil {
+		return err
+	}
+	defer call.Response.Body.Close()
+
+	result, err := ioutil.ReadAll(call.Response.Body)
+	if err != nil {
+		c.cancelOnDone(ctx, api, call.QueryID)
+		return err
+	}
+	call.Result = result
+	c.debugResponse(ctx, call.Response.StatusCode, result)
+	return nil
+}
+
+// openCall RoundTrip sending the call to druid, the body of the successful response is left unread.
+func (c *Client) openCall(ctx context.Context, call *Call) (err error) {
+	api := nativeAPI
+	if call.SQL != nil {
+		api = sqlAPI
+	}
+	call.Response, call.QueryID, err = c.send(ctx, api, call.Request)
+	if call.Response == nil {
+		return err
+	}
+	call.received, _ = call.Response.Body.(*countingBody)
+	call.Meta = newResponseMeta(call.Response)
+	missing := len(call.Meta.Context.MissingSegments) > 0
+	if err == nil && missing && c.MissingSegmentsAsError && !(c.RequeryPartial && canRequery(call.Query)) {
+		call.Response.Body.Close()
+		return &MissingSegmentsError{QueryID: call.QueryID, MissingSegments: call.Meta.Context.MissingSegments}
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package godruid
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_Use(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"tenant"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"Unsupported operation","errorMessage":"missing tenant filter"}`))
+			return
+		}
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`))
+	}))
+	defer server.Close()
+
+	var trace []string
+	tenantFilter := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, call *Call) error {
+			trace = append(trace, "tenant")
+			if q, ok := call.Query.(*QueryTimeseries); ok {
+				q.Filter = FilterAnd(q.Filter, FilterSelector("tenant", "t1"))
+				if err := call.Encode(); err != nil {
+					return err
+				}
+			}
+			return next(ctx, call)
+		}
+	}
+	audit := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, call *Call) error {
+			trace = append(trace, "audit:before")
+			err := next(ctx, call)
+			status := 0
+			if call.Response != nil {
+				status = call.Response.StatusCode
+			}
+			trace = append(trace, "audit:after:"+http.StatusText(status))
+			if err != nil && !IsUnsupportedOperation(err) {
+				t.Errorf("middleware got error %v, want decoded druid error", err)
+			}
+			return err
+		}
+	}
+
+	client := &Client{Url: server.URL, HttpClient: server.Client()}
+	client.Use(audit, tenantFilter)
+	query := &QueryTimeseries{Granularity: GranAll}
+	if err := client.Query(query); err != nil {
+		t.Fatalf("Client.Query() error = %v", err)
+	}
+	if query.QueryResult[0].Result["count"] != 1.0 {
+		t.Errorf("Client.Query() result = %v", query.QueryResult)
+	}
+
+	if _, err := client.QueryRaw([]byte(`{"queryType":"timeseries"}`)); !IsUnsupportedOperation(err) {
+		t.Errorf("Client.QueryRaw() error = %v, want unsupported operation", err)
+	}
+
+	want := []string{"audit:before", "tenant", "audit:after:OK", "audit:before", "tenant", "audit:after:Bad Request"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("middleware trace = %v, want %v", trace, want)
+	}
+}
@@ -2,18 +2,32 @@ package godruid
 
 import (
 	"encoding/json"
-	"strings"
 	"time"
 )
 
 // Check http://druid.io/docs/0.6.154/Querying.html#query-operators for detail description.
 
 // The Query interface stands for any kinds of druid query.
+// Implement it to run extension query types through Client, and RegisterQueryType to decode them by DecodeQuery.
 type Query interface {
-	setup()
-	onResponse(content []byte) error
+	// Setup prepare the query before it is sent, e.g. fill its queryType
+	Setup()
+	// OnResponse decode the druid response and store the result
+	OnResponse(content []byte) error
 	GetRawJSON() []byte
-	shouldCache() bool
+	// ShouldCache whether the result can be stored into Client.ResultCache
+	ShouldCache() bool
+}
+
+// The DataSourceSetter interface stands for any kinds of druid query whose datasource can be set by Client.DataSource
+type DataSourceSetter interface {
+	SetDataSource(ds DataSource)
+}
+
+// The DataSourceGetter interface stands for any kinds of druid query whose own datasource is kept,
+// Client.DataSource is only set to the queries of it without datasource.
+type DataSourceGetter interface {
+	GetDataSource() DataSource
 }
 
 // The QueryCanAggregate interface stands for any kinds of druid query that can using aggregations and post aggregations
@@ -35,6 +49,8 @@ const (
 	TIMEBOUNDARY    QueryType = "timeBoundary"
 	SELECT          QueryType = "select"
 	SCAN            QueryType = "scan"
+
+	DATASOURCEMETADATA QueryType = "dataSourceMetadata"
 )
 
 // Context constants
@@ -42,8 +58,10 @@ const (
 	TIMEOUT                  = "timeout"
 	SKIPEMPTYBUCKETS         = "skipEmptyBuckets"
 	QUERYID                  = "queryId"
+	SQLQUERYID               = "sqlQueryId"
 	POPULATECACHE            = "populateCache"
 	POPULATERESULTLEVELCACHE = "populateResultLevelCache"
+	UNCOVEREDINTERVALSLIMIT  = "uncoveredIntervalsLimit"
 )
 
 // ---------------------------------
@@ -52,7 +70,7 @@ const (
 
 type QueryGroupBy struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
+	DataSource       DataSource             `json:"dataSource"`
 	Dimensions       []DimSpec              `json:"dimensions"`
 	Granularity      Granlarity             `json:"granularity"`
 	LimitSpec        *Limit                 `json:"limitSpec,omitempty"`
@@ -80,19 +98,14 @@ func intervalShouldCache(intervals []string) bool {
 	}
 
 	ret := true
-	nowTimeNano := time.Now().UnixNano()
+	now := time.Now()
 	for _, interval := range intervals {
-		timeRange := strings.SplitN(interval, "/", 2)
-		if len(timeRange) != 2 {
-			ret = false
-			break
-		}
-		endTime, err := time.Parse(time.RFC3339, timeRange[1])
+		i, err := ParseISOInterval(interval)
 		if err != nil {
 			ret = false
 			break
 		}
-		if nowTimeNano < endTime.UnixNano() {
+		if now.Before(i.End()) {
 			ret = false
 			break
 		}
@@ -101,10 +114,12 @@ func intervalShouldCache(intervals []string) bool {
 	return ret
 }
 
-func (q *QueryGroupBy) setup()             { q.QueryType = GROUPBY }
-func (q *QueryGroupBy) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QueryGroupBy) GetRawJSON() []byte { return q.RawJSON }
-func (q *QueryGroupBy) onResponse(content []byte) error {
+func (q *QueryGroupBy) Setup()                      { q.QueryType = GROUPBY }
+func (q *QueryGroupBy) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QueryGroupBy) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryGroupBy) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryGroupBy) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryGroupBy) OnResponse(content []byte) error {
 	res := new([]GroupbyItem)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -132,7 +147,7 @@ func (q *QueryGroupBy) AddSubtotalsSpec(subTotoalGroupKeys []string) {
 
 type QuerySearch struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
+	DataSource       DataSource             `json:"dataSource"`
 	Granularity      Granlarity             `json:"granularity"`
 	Filter           *Filter                `json:"filter,omitempty"`
 	Intervals        Intervals              `json:"intervals"`
@@ -155,10 +170,12 @@ type DimValue struct {
 	Value     string `json:"value"`
 }
 
-func (q *QuerySearch) setup()             { q.QueryType = SEARCH }
-func (q *QuerySearch) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QuerySearch) GetRawJSON() []byte { return q.RawJSON }
-func (q *QuerySearch) onResponse(content []byte) error {
+func (q *QuerySearch) Setup()                      { q.QueryType = SEARCH }
+func (q *QuerySearch) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QuerySearch) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QuerySearch) GetDataSource() DataSource   { return q.DataSource }
+func (q *QuerySearch) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QuerySearch) OnResponse(content []byte) error {
 	res := new([]SearchItem)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -175,7 +192,7 @@ func (q *QuerySearch) onResponse(content []byte) error {
 
 type QuerySegmentMetadata struct {
 	QueryType      QueryType              `json:"queryType"`
-	DataSource     string                 `json:"dataSource"`
+	DataSource     DataSource             `json:"dataSource"`
 	Intervals      Intervals              `json:"intervals"`
 	ToInclude      *ToInclude             `json:"toInclude,omitempty"`
 	Merge          interface{}            `json:"merge,omitempty"`
@@ -197,10 +214,12 @@ type ColumnItem struct {
 	Cardinality interface{} `json:"cardinality"`
 }
 
-func (q *QuerySegmentMetadata) setup()             { q.QueryType = "segmentMetadata" }
-func (q *QuerySegmentMetadata) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QuerySegmentMetadata) GetRawJSON() []byte { return q.RawJSON }
-func (q *QuerySegmentMetadata) onResponse(content []byte) error {
+func (q *QuerySegmentMetadata) Setup()                      { q.QueryType = "segmentMetadata" }
+func (q *QuerySegmentMetadata) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QuerySegmentMetadata) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QuerySegmentMetadata) GetDataSource() DataSource   { return q.DataSource }
+func (q *QuerySegmentMetadata) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QuerySegmentMetadata) OnResponse(content []byte) error {
 	res := new([]SegmentMetaData)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -215,10 +234,18 @@ func (q *QuerySegmentMetadata) onResponse(content []byte) error {
 // TimeBoundary Query
 // ---------------------------------
 
+// Bound of time boundary query
+const (
+	BoundMinTime = "minTime"
+	BoundMaxTime = "maxTime"
+)
+
 type QueryTimeBoundary struct {
 	QueryType   QueryType              `json:"queryType"`
-	DataSource  string                 `json:"dataSource"`
+	DataSource  DataSource             `json:"dataSource"`
 	Bound       string                 `json:"bound,omitempty"`
+	Filter      *Filter                `json:"filter,omitempty"`
+	Intervals   Intervals              `json:"intervals,omitempty"`
 	Context     map[string]interface{} `json:"context,omitempty"`
 	QueryResult []TimeBoundaryItem     `json:"-"`
 	RawJSON     []byte
@@ -229,15 +256,18 @@ type TimeBoundaryItem struct {
 	Result    TimeBoundary `json:"result"`
 }
 
+// TimeBoundary result of time boundary query, the time not queried by Bound is zero
 type TimeBoundary struct {
-	MinTime string `json:"minTime"`
-	MaxTime string `json:"minTime"`
+	MinTime time.Time `json:"minTime"`
+	MaxTime time.Time `json:"maxTime"`
 }
 
-func (q *QueryTimeBoundary) setup()             { q.QueryType = TIMEBOUNDARY }
-func (q *QueryTimeBoundary) shouldCache() bool  { return false }
-func (q *QueryTimeBoundary) GetRawJSON() []byte { return q.RawJSON }
-func (q *QueryTimeBoundary) onResponse(content []byte) error {
+func (q *QueryTimeBoundary) Setup()                      { q.QueryType = TIMEBOUNDARY }
+func (q *QueryTimeBoundary) ShouldCache() bool           { return false }
+func (q *QueryTimeBoundary) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryTimeBoundary) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryTimeBoundary) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryTimeBoundary) OnResponse(content []byte) error {
 	res := new([]TimeBoundaryItem)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -248,13 +278,74 @@ func (q *QueryTimeBoundary) onResponse(content []byte) error {
 	return nil
 }
 
+// MinTime min time of the result, zero when the result is empty or not queried
+func (q *QueryTimeBoundary) MinTime() time.Time {
+	if len(q.QueryResult) == 0 {
+		return time.Time{}
+	}
+	return q.QueryResult[0].Result.MinTime
+}
+
+// MaxTime max time of the result, zero when the result is empty or not queried
+func (q *QueryTimeBoundary) MaxTime() time.Time {
+	if len(q.QueryResult) == 0 {
+		return time.Time{}
+	}
+	return q.QueryResult[0].Result.MaxTime
+}
+
+// ---------------------------------
+// DataSourceMetadata Query
+// ---------------------------------
+
+type QueryDataSourceMetadata struct {
+	QueryType   QueryType                `json:"queryType"`
+	DataSource  DataSource               `json:"dataSource"`
+	Context     map[string]interface{}   `json:"context,omitempty"`
+	QueryResult []DataSourceMetadataItem `json:"-"`
+	RawJSON     []byte
+}
+
+type DataSourceMetadataItem struct {
+	Timestamp string             `json:"timestamp"`
+	Result    DataSourceMetadata `json:"result"`
+}
+
+type DataSourceMetadata struct {
+	MaxIngestedEventTime time.Time `json:"maxIngestedEventTime"`
+}
+
+func (q *QueryDataSourceMetadata) Setup()                      { q.QueryType = DATASOURCEMETADATA }
+func (q *QueryDataSourceMetadata) ShouldCache() bool           { return false }
+func (q *QueryDataSourceMetadata) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryDataSourceMetadata) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryDataSourceMetadata) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryDataSourceMetadata) OnResponse(content []byte) error {
+	res := new([]DataSourceMetadataItem)
+	err := json.Unmarshal(content, res)
+	if err != nil {
+		return err
+	}
+	q.QueryResult = *res
+	q.RawJSON = content
+	return nil
+}
+
+// MaxIngestedEventTime timestamp of the latest ingested event, zero when the result is empty
+func (q *QueryDataSourceMetadata) MaxIngestedEventTime() time.Time {
+	if len(q.QueryResult) == 0 {
+		return time.Time{}
+	}
+	return q.QueryResult[0].Result.MaxIngestedEventTime
+}
+
 // ---------------------------------
 // Timeseries Query
 // ---------------------------------
 
 type QueryTimeseries struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
+	DataSource       DataSource             `json:"dataSource"`
 	Granularity      Granlarity             `json:"granularity"`
 	Filter           *Filter                `json:"filter,omitempty"`
 	Aggregations     []Aggregation          `json:"aggregations"`
@@ -271,10 +362,12 @@ type Timeseries struct {
 	Result    map[string]interface{} `json:"result"`
 }
 
-func (q *QueryTimeseries) setup()             { q.QueryType = TIMESERIES }
-func (q *QueryTimeseries) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QueryTimeseries) GetRawJSON() []byte { return q.RawJSON }
-func (q *QueryTimeseries) onResponse(content []byte) error {
+func (q *QueryTimeseries) Setup()                      { q.QueryType = TIMESERIES }
+func (q *QueryTimeseries) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QueryTimeseries) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryTimeseries) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryTimeseries) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryTimeseries) OnResponse(content []byte) error {
 	res := new([]Timeseries)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -299,7 +392,7 @@ func (q *QueryTimeseries) AddPostAggregation(postAgg PostAggregation) {
 
 type QueryTopN struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
+	DataSource       DataSource             `json:"dataSource"`
 	Granularity      Granlarity             `json:"granularity"`
 	Dimension        DimSpec                `json:"dimension"`
 	Threshold        int                    `json:"threshold"`
@@ -319,10 +412,12 @@ type TopNItem struct {
 	Result    []map[string]interface{} `json:"result"`
 }
 
-func (q *QueryTopN) setup()             { q.QueryType = TOPN }
-func (q *QueryTopN) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QueryTopN) GetRawJSON() []byte { return q.RawJSON }
-func (q *QueryTopN) onResponse(content []byte) error {
+func (q *QueryTopN) Setup()                      { q.QueryType = TOPN }
+func (q *QueryTopN) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QueryTopN) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryTopN) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryTopN) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryTopN) OnResponse(content []byte) error {
 	res := new([]TopNItem)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -345,7 +440,7 @@ func (q *QueryTopN) AddPostAggregation(postAgg PostAggregation) {
 
 type QuerySelect struct {
 	QueryType      QueryType              `json:"queryType"`
-	DataSource     string                 `json:"dataSource"`
+	DataSource     DataSource             `json:"dataSource"`
 	Intervals      Intervals              `json:"intervals"`
 	Filter         *Filter                `json:"filter,omitempty"`
 	Dimensions     []DimSpec              `json:"dimensions"`
@@ -378,10 +473,12 @@ type SelectEvent struct {
 	Event     map[string]interface{} `json:"event"`
 }
 
-func (q *QuerySelect) setup()             { q.QueryType = SELECT }
-func (q *QuerySelect) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QuerySelect) GetRawJSON() []byte { return q.RawJSON }
-func (q *QuerySelect) onResponse(content []byte) error {
+func (q *QuerySelect) Setup()                      { q.QueryType = SELECT }
+func (q *QuerySelect) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QuerySelect) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QuerySelect) GetDataSource() DataSource   { return q.DataSource }
+func (q *QuerySelect) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QuerySelect) OnResponse(content []byte) error {
 	res := new([]SelectBlob)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -402,7 +499,7 @@ func (q *QuerySelect) onResponse(content []byte) error {
 
 type QueryScan struct {
 	QueryType      QueryType              `json:"queryType"`
-	DataSource     string                 `json:"dataSource"`
+	DataSource     DataSource             `json:"dataSource"`
 	Limit          int64                  `json:"limit,omitempty"`
 	BatchSize      int64                  `json:"batchSize,omitempty"`
 	Columns        []string               `json:"columns,omitempty"`
@@ -417,16 +514,24 @@ type QueryScan struct {
 	RawJSON        []byte
 }
 
+// Scan query result formats
+const (
+	ScanResultFormatList          = "list"
+	ScanResultFormatCompactedList = "compactedList"
+)
+
 type ScanBlob struct {
 	SegmentID string                   `json:"segmentId"`
 	Columns   []string                 `json:"columns"`
 	Events    []map[string]interface{} `json:"events"`
 }
 
-func (q *QueryScan) setup()             { q.QueryType = SCAN }
-func (q *QueryScan) shouldCache() bool  { return intervalShouldCache(q.Intervals) }
-func (q *QueryScan) GetRawJSON() []byte { return q.RawJSON }
-func (q *QueryScan) onResponse(content []byte) error {
+func (q *QueryScan) Setup()                      { q.QueryType = SCAN }
+func (q *QueryScan) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *QueryScan) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *QueryScan) GetDataSource() DataSource   { return q.DataSource }
+func (q *QueryScan) GetRawJSON() []byte          { return q.RawJSON }
+func (q *QueryScan) OnResponse(content []byte) error {
 	res := new([]ScanBlob)
 	err := json.Unmarshal(content, res)
 	if err != nil {
@@ -0,0 +1,78 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeBoundary(t *testing.T) {
+	var req map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"minTime":"2019-01-01T00:00:00.000Z","maxTime":"2019-12-31T23:59:00.000Z"}}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	query := &QueryTimeBoundary{
+		Filter:    FilterSelector("page", "Druid"),
+		Intervals: []string{"2019-01-01T00:00:00Z/2020-01-01T00:00:00Z"},
+	}
+	if err := client.Query(query); err != nil {
+		t.Fatalf("Client.Query() error = %v", err)
+	}
+	if req["queryType"] != "timeBoundary" || req["filter"] == nil || req["intervals"] == nil {
+		t.Errorf("request = %v", req)
+	}
+	if want := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC); !query.MinTime().Equal(want) {
+		t.Errorf("MinTime() = %v, want %v", query.MinTime(), want)
+	}
+	if want := time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC); !query.MaxTime().Equal(want) {
+		t.Errorf("MaxTime() = %v, want %v", query.MaxTime(), want)
+	}
+}
+
+func TestQueryTimeBoundary_Bound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"timestamp":"2019-12-31T23:59:00.000Z","result":{"maxTime":"2019-12-31T23:59:00.000Z"}}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	query := &QueryTimeBoundary{Bound: BoundMaxTime}
+	if err := client.Query(query); err != nil {
+		t.Fatalf("Client.Query() error = %v", err)
+	}
+	if !query.MinTime().IsZero() || query.MaxTime().IsZero() {
+		t.Errorf("MinTime() = %v, MaxTime() = %v", query.MinTime(), query.MaxTime())
+	}
+}
+
+func TestQueryDataSourceMetadata(t *testing.T) {
+	var req map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		w.Write([]byte(`[{"timestamp":"2019-12-31T23:59:00.000Z","result":{"maxIngestedEventTime":"2019-12-31T23:59:00.000Z"}}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	res, err := client.Do(context.Background(), &QueryDataSourceMetadata{})
+	if err != nil {
+		t.Fatalf("Client.Do() error = %v", err)
+	}
+	if req["queryType"] != "dataSourceMetadata" || req["dataSource"] != "wiki" {
+		t.Errorf("request = %v", req)
+	}
+	items := res.DataSourceMetadata()
+	if want := time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC); len(items) != 1 || !items[0].Result.MaxIngestedEventTime.Equal(want) || res.Rows() != 1 {
+		t.Errorf("DataSourceMetadata() = %v, want %v", items, want)
+	}
+}
@@ -0,0 +1,263 @@
+package godruid
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Values of QueryContext.Vectorize
+const (
+	VectorizeTrue  = "true"
+	VectorizeFalse = "false"
+	VectorizeForce = "force"
+)
+
+// Values of QueryContext.GroupByStrategy
+const (
+	GroupByStrategyV1 = "v1"
+	GroupByStrategyV2 = "v2"
+)
+
+// QueryContext typed druid query context, the unset(zero or nil) fields are not sent.
+// Use Map to set it into the Context of queries, or set it as Client.DefaultContext.
+type QueryContext struct {
+	Timeout                  time.Duration `json:"-"` // sent in milliseconds
+	Priority                 *int          `json:"priority,omitempty"`
+	Lane                     string        `json:"lane,omitempty"`
+	QueryID                  string        `json:"queryId,omitempty"`
+	SQLQueryID               string        `json:"sqlQueryId,omitempty"`
+	UseCache                 *bool         `json:"useCache,omitempty"`
+	PopulateCache            *bool         `json:"populateCache,omitempty"`
+	UseResultLevelCache      *bool         `json:"useResultLevelCache,omitempty"`
+	PopulateResultLevelCache *bool         `json:"populateResultLevelCache,omitempty"`
+	BySegment                *bool         `json:"bySegment,omitempty"`
+	Finalize                 *bool         `json:"finalize,omitempty"`
+	Vectorize                string        `json:"vectorize,omitempty"`
+	VectorSize               int           `json:"vectorSize,omitempty"`
+	MaxScatterGatherBytes    int64         `json:"maxScatterGatherBytes,omitempty"`
+	MaxQueuedBytes           int64         `json:"maxQueuedBytes,omitempty"`
+	UncoveredIntervalsLimit  int           `json:"uncoveredIntervalsLimit,omitempty"`
+	SkipEmptyBuckets         *bool         `json:"skipEmptyBuckets,omitempty"`
+	GroupByStrategy          string        `json:"groupByStrategy,omitempty"`
+	SortByDimsFirst          *bool         `json:"sortByDimsFirst,omitempty"`
+	GrandTotal               *bool         `json:"grandTotal,omitempty"`
+	SQLTimeZone              string        `json:"sqlTimeZone,omitempty"`
+	EnableJoinFilterRewrite  *bool         `json:"enableJoinFilterRewrite,omitempty"`
+	EnableJoinFilterPushDown *bool         `json:"enableJoinFilterPushDown,omitempty"`
+	// Extra the other context keys, the typed fields win when set both
+	Extra map[string]interface{} `json:"-"`
+}
+
+// NewQueryContext new empty query context to build with the With methods
+func NewQueryContext() *QueryContext {
+	return &QueryContext{}
+}
+
+// WithTimeout set the query timeout, sent as `timeout` in milliseconds
+func (qc *QueryContext) WithTimeout(timeout time.Duration) *QueryContext {
+	qc.Timeout = timeout
+	return qc
+}
+
+// WithPriority set the query priority, higher priority queries get more resources
+func (qc *QueryContext) WithPriority(priority int) *QueryContext {
+	qc.Priority = &priority
+	return qc
+}
+
+// WithLane set the lane of the query for query laning
+func (qc *QueryContext) WithLane(lane string) *QueryContext {
+	qc.Lane = lane
+	return qc
+}
+
+// WithQueryID set the queryId, it is ignored in Client.DefaultContext
+func (qc *QueryContext) WithQueryID(queryID string) *QueryContext {
+	qc.QueryID = queryID
+	return qc
+}
+
+// WithSQLQueryID set the sqlQueryId of sql queries, it is ignored in Client.DefaultContext
+func (qc *QueryContext) WithSQLQueryID(queryID string) *QueryContext {
+	qc.SQLQueryID = queryID
+	return qc
+}
+
+// WithUseCache set whether the segment level cache of druid is read
+func (qc *QueryContext) WithUseCache(use bool) *QueryContext {
+	qc.UseCache = &use
+	return qc
+}
+
+// WithPopulateCache set whether the results are saved into the segment level cache of druid
+func (qc *QueryContext) WithPopulateCache(populate bool) *QueryContext {
+	qc.PopulateCache = &populate
+	return qc
+}
+
+// WithUseResultLevelCache set whether the result level cache of druid is read
+func (qc *QueryContext) WithUseResultLevelCache(use bool) *QueryContext {
+	qc.UseResultLevelCache = &use
+	return qc
+}
+
+// WithPopulateResultLevelCache set whether the results are saved into the result level cache of druid
+func (qc *QueryContext) WithPopulateResultLevelCache(populate bool) *QueryContext {
+	qc.PopulateResultLevelCache = &populate
+	return qc
+}
+
+// WithBySegment set whether the results are returned by segment
+func (qc *QueryContext) WithBySegment(bySegment bool) *QueryContext {
+	qc.BySegment = &bySegment
+	return qc
+}
+
+// WithFinalize set whether the aggregation results are finalized, such as the cardinality of hyperUnique
+func (qc *QueryContext) WithFinalize(finalize bool) *QueryContext {
+	qc.Finalize = &finalize
+	return qc
+}
+
+// WithVectorize set vectorize to one of VectorizeTrue, VectorizeFalse and VectorizeForce
+func (qc *QueryContext) WithVectorize(vectorize string) *QueryContext {
+	qc.Vectorize = vectorize
+	return qc
+}
+
+// WithVectorSize set the row batch size of vectorized queries
+func (qc *QueryContext) WithVectorSize(size int) *QueryContext {
+	qc.VectorSize = size
+	return qc
+}
+
+// WithMaxScatterGatherBytes set the max bytes gathered from the data nodes
+func (qc *QueryContext) WithMaxScatterGatherBytes(bytes int64) *QueryContext {
+	qc.MaxScatterGatherBytes = bytes
+	return qc
+}
+
+// WithMaxQueuedBytes set the max bytes queued per query before exerting backpressure
+func (qc *QueryContext) WithMaxQueuedBytes(bytes int64) *QueryContext {
+	qc.MaxQueuedBytes = bytes
+	return qc
+}
+
+// WithUncoveredIntervalsLimit set the max uncovered intervals reported in the response context, see ResponseContext
+func (qc *QueryContext) WithUncoveredIntervalsLimit(limit int) *QueryContext {
+	qc.UncoveredIntervalsLimit = limit
+	return qc
+}
+
+// WithSkipEmptyBuckets set whether the time buckets without data are skipped by timeseries queries
+func (qc *QueryContext) WithSkipEmptyBuckets(skip bool) *QueryContext {
+	qc.SkipEmptyBuckets = &skip
+	return qc
+}
+
+// WithGroupByStrategy set groupByStrategy to GroupByStrategyV1 or GroupByStrategyV2
+func (qc *QueryContext) WithGroupByStrategy(strategy string) *QueryContext {
+	qc.GroupByStrategy = strategy
+	return qc
+}
+
+// WithSortByDimsFirst set whether groupBy results are sorted by dimensions before the timestamp
+func (qc *QueryContext) WithSortByDimsFirst(sortByDimsFirst bool) *QueryContext {
+	qc.SortByDimsFirst = &sortByDimsFirst
+	return qc
+}
+
+// WithGrandTotal set whether timeseries queries return a grand total row
+func (qc *QueryContext) WithGrandTotal(grandTotal bool) *QueryContext {
+	qc.GrandTotal = &grandTotal
+	return qc
+}
+
+// WithSQLTimeZone set the time zone of sql queries, such as `Asia/Shanghai`
+func (qc *QueryContext) WithSQLTimeZone(tz string) *QueryContext {
+	qc.SQLTimeZone = tz
+	return qc
+}
+
+// WithEnableJoinFilterRewrite set whether filters on the right columns of joins are rewritten
+func (qc *QueryContext) WithEnableJoinFilterRewrite(enable bool) *QueryContext {
+	qc.EnableJoinFilterRewrite = &enable
+	return qc
+}
+
+// WithEnableJoinFilterPushDown set whether filters of joins are pushed down to the joined datasources
+func (qc *QueryContext) WithEnableJoinFilterPushDown(enable bool) *QueryContext {
+	qc.EnableJoinFilterPushDown = &enable
+	return qc
+}
+
+// WithValue set a context key not typed by QueryContext
+func (qc *QueryContext) WithValue(key string, value interface{}) *QueryContext {
+	if qc.Extra == nil {
+		qc.Extra = map[string]interface{}{}
+	}
+	qc.Extra[key] = value
+	return qc
+}
+
+// Map the context map to set into the Context of queries, nil for nil qc
+func (qc *QueryContext) Map() map[string]interface{} {
+	if qc == nil {
+		return nil
+	}
+	ret := map[string]interface{}{}
+	for k, v := range qc.Extra {
+		ret[k] = v
+	}
+
+	data, _ := json.Marshal(qc)
+	var typed map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.Decode(&typed)
+	for k, v := range typed {
+		ret[k] = v
+	}
+	if qc.Timeout > 0 {
+		ret[TIMEOUT] = int64(qc.Timeout / time.Millisecond)
+	}
+	return ret
+}
+
+// MergeContext set the keys of defaults missing in queryCtx, queryCtx is created when nil
+func MergeContext(queryCtx, defaults map[string]interface{}) map[string]interface{} {
+	if queryCtx == nil {
+		queryCtx = map[string]interface{}{}
+	}
+	for k, v := range defaults {
+		if _, ok := queryCtx[k]; !ok {
+			queryCtx[k] = v
+		}
+	}
+	return queryCtx
+}
+
+// defaultContext the context map of Client.DefaultContext, without the query ids which must be unique per query
+func (c *Client) defaultContext() map[string]interface{} {
+	defaults := c.DefaultContext.Map()
+	delete(defaults, QUERYID)
+	delete(defaults, SQLQUERYID)
+	return defaults
+}
+
+// cacheKey key of the request in ResultCache, the default context is included for it may change the result
+func (c *Client) cacheKey(req []byte) string {
+	defaults := c.defaultContext()
+	if len(defaults) == 0 {
+		return dataKey(req)
+	}
+	var query map[string]interface{}
+	if err := json.Unmarshal(req, &query); err != nil {
+		return dataKey(req)
+	}
+	queryCtx, _ := query["context"].(map[string]interface{})
+	query["context"] = MergeContext(queryCtx, defaults)
+	data, _ := json.Marshal(query)
+	return dataKey(data)
+}
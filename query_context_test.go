@@ -0,0 +1,118 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryContext_Map(t *testing.T) {
+	qc := NewQueryContext().
+		WithTimeout(30*time.Second).
+		WithPriority(-1).
+		WithLane("low").
+		WithUseCache(false).
+		WithFinalize(true).
+		WithVectorize(VectorizeForce).
+		WithMaxScatterGatherBytes(1<<40).
+		WithGroupByStrategy(GroupByStrategyV2).
+		WithGrandTotal(true).
+		WithSQLTimeZone("Asia/Shanghai").
+		WithEnableJoinFilterRewrite(false).
+		WithValue("minTopNThreshold", 100).
+		WithValue("lane", "ignored")
+	data, _ := json.Marshal(qc.Map())
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+	want := map[string]interface{}{
+		"timeout":                 30000.0,
+		"priority":                -1.0,
+		"lane":                    "low",
+		"useCache":                false,
+		"finalize":                true,
+		"vectorize":               "force",
+		"maxScatterGatherBytes":   float64(1 << 40),
+		"groupByStrategy":         "v2",
+		"grandTotal":              true,
+		"sqlTimeZone":             "Asia/Shanghai",
+		"enableJoinFilterRewrite": false,
+		"minTopNThreshold":        100.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryContext.Map() = %v, want %v", got, want)
+	}
+	if m := (*QueryContext)(nil).Map(); m != nil {
+		t.Errorf("nil QueryContext.Map() = %v", m)
+	}
+	if m := NewQueryContext().Map(); len(m) != 0 {
+		t.Errorf("empty QueryContext.Map() = %v", m)
+	}
+}
+
+func TestClient_DefaultContext(t *testing.T) {
+	var reqCtx map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Context map[string]interface{} `json:"context"`
+		}
+		json.Unmarshal(body, &req)
+		reqCtx = req.Context
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		Url:            server.URL,
+		HttpClient:     server.Client(),
+		DataSource:     "wiki",
+		DefaultContext: NewQueryContext().WithTimeout(time.Hour).WithPriority(10).WithUseCache(false).WithQueryID("fixed"),
+	}
+	query := &QueryTimeseries{
+		Granularity: GranAll,
+		Intervals:   []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"},
+		Context:     NewQueryContext().WithPriority(20).Map(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := client.QueryContext(ctx, query); err != nil {
+		t.Fatalf("Client.QueryContext() error = %v", err)
+	}
+	if reqCtx["priority"] != 20.0 || reqCtx["useCache"] != false {
+		t.Errorf("context = %v", reqCtx)
+	}
+	if timeout, _ := reqCtx["timeout"].(float64); timeout <= 0 || timeout > 60000 {
+		t.Errorf("context timeout = %v, want the deadline of ctx", reqCtx["timeout"])
+	}
+	if id, _ := reqCtx[QUERYID].(string); id == "" || id == "fixed" {
+		t.Errorf("context queryId = %v, want a generated one", reqCtx[QUERYID])
+	}
+	if _, ok := query.Context["useCache"]; ok {
+		t.Errorf("query context modified to %v", query.Context)
+	}
+}
+
+func TestClient_cacheKey(t *testing.T) {
+	req := []byte(`{"queryType":"timeseries","context":{"priority":1}}`)
+	client := &Client{}
+	if client.cacheKey(req) != dataKey(req) {
+		t.Errorf("Client.cacheKey() without default context differs from dataKey()")
+	}
+	client.DefaultContext = NewQueryContext().WithFinalize(false)
+	if client.cacheKey(req) == dataKey(req) {
+		t.Errorf("Client.cacheKey() with default context equals dataKey()")
+	}
+	client.DefaultContext = NewQueryContext().WithPriority(2)
+	if client.cacheKey(req) != dataKey(req) {
+		t.Errorf("Client.cacheKey() with overridden default context differs from dataKey()")
+	}
+	client.DefaultContext = NewQueryContext().WithQueryID("fixed").WithSQLQueryID("fixed")
+	if client.cacheKey(req) != dataKey(req) {
+		t.Errorf("Client.cacheKey() with default query ids differs from dataKey()")
+	}
+}
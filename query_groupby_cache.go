@@ -1,6 +1,7 @@
 package godruid
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -123,55 +124,95 @@ func (q *QueryGroupBy) LoadQueryResult(pRows []PersistenceRow) error {
 
 // CacheQuery query with attached cached
 func (q *QueryGroupBy) CacheQuery(c *Client, target string, writeback bool) error {
+	return q.CacheQueryContext(context.Background(), c, target, writeback)
+}
+
+// CacheQueryContext query with attached cached, the druid queries are canceled when ctx is done.
+func (q *QueryGroupBy) CacheQueryContext(ctx context.Context, c *Client, target string, writeback bool) (err error) {
 	if c.GroupByCache == nil || target == "" {
-		return c.Query(q)
+		return c.QueryContext(ctx, q)
 	}
 
-	q.setup()
+	q.Setup()
 	setDataSource(q, c.DataSource)
 
+	ctx, span := c.tracer().Start(ctx, SpanCacheQuery)
+	span.SetAttribute("cache.target", target)
+	defer func() { span.End(err) }()
+
 	c3 := q.conditionGroupDims()
 	c4 := q.conditionAggNames()
 	c5 := q.conditionPostAggNames()
 	c6 := q.conditionFilterMD5()
 
-	intervalSlots, err := q.DistributeIntervalSlots()
+	intervalSlots, err := q.DistributeIntervalSlotsBy(c.slotStrategy())
 	if err != nil {
 		return err
 	}
 
+	// results of the slots are merged into a query without intervals and result,
+	// which would overlap with the slots.
+	merged := *q
+	merged.Intervals, merged.QueryResult = nil, nil
 	for _, i := range intervalSlots {
-		selectConditions := []Condition{q.conditionTimePos(i.TimePos), q.conditionTimeLen(i.TimeLen), c3, c4, c5, c6}
-		cacheSelectQuery := CacheSelectQuery{Target: target, Conditions: selectConditions}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		newQ := *q
 		newQ.Intervals = []string{i.ToInterval()}
-		// * 如果查询成功,则将结果merge到queryResult中。查询失败则调用原始查询函数进行查询
-		ret := c.GroupByCache.Select(cacheSelectQuery)
-		if len(ret) > 0 {
-			newQ.setup()
-			setDataSource(&newQ, c.DataSource)
-			newQ.LoadQueryResult(ret)
-		} else {
-			c.logger().Debugf("[%s] no entries cached by index:%v", "QueryGroupBy.CacheQuery", target)
-			err := c.Query(&newQ)
-			if err != nil {
+		if !i.wholeSeconds() {
+			// slots of sub-second endpoints can not be keyed by timePos and timeLen in seconds, they are not cached
+			if err := c.QueryContext(ctx, &newQ); err != nil {
 				return err
 			}
-			if writeback {
-				rows, _ := newQ.PersistenceRows()
-				c.logger().Debugf("[%s] save query result to cache by index:%v, count:%d", "QueryGroupBy.CacheQuery", target, len(rows))
-				err := c.GroupByCache.InsertBatch(target, rows, 0)
-
-				if err != nil {
-					return err
-				}
+		} else {
+			selectConditions := []Condition{q.conditionTimePos(i.TimePos), q.conditionTimeLen(i.TimeLen), c3, c4, c5, c6}
+			cacheSelectQuery := CacheSelectQuery{Target: target, Conditions: selectConditions}
+			if err := newQ.cacheQuerySlot(ctx, c, cacheSelectQuery, writeback); err != nil {
+				return err
 			}
 		}
-		if err := q.Merge(&newQ); err != nil {
+		if err := merged.Merge(&newQ); err != nil {
 			return err
 		}
 	}
 
+	q.QueryResult = merged.QueryResult
+	return nil
+}
+
+// cacheQuerySlot load the result of the single interval slot query from cache, or query it from druid when not cached.
+func (q *QueryGroupBy) cacheQuerySlot(ctx context.Context, c *Client, cacheSelectQuery CacheSelectQuery, writeback bool) (err error) {
+	target := cacheSelectQuery.Target
+	ctx, span := c.tracer().Start(ctx, SpanSlot)
+	span.SetAttribute("druid.interval", q.Intervals[0])
+	defer func() { span.End(err) }()
+
+	// * 如果查询成功,则将结果merge到queryResult中。查询失败则调用原始查询函数进行查询
+	_, lookupSpan := c.tracer().Start(ctx, SpanCacheLookup)
+	ret := c.GroupByCache.Select(cacheSelectQuery)
+	lookupSpan.SetAttribute("cache.hit", len(ret) > 0)
+	lookupSpan.End(nil)
+	if c.Metrics != nil {
+		c.Metrics.ObserveSlot(SlotMetric{DataSource: dataSourceName(q.DataSource), Target: target, Hit: len(ret) > 0, Rows: len(ret)})
+	}
+	if len(ret) > 0 {
+		q.Setup()
+		setDataSource(q, c.DataSource)
+		return q.LoadQueryResult(ret)
+	}
+
+	c.logger().Debugf("[%s] no entries cached by index:%v", "QueryGroupBy.CacheQuery", target)
+	call, err := c.queryCall(ctx, q)
+	if err != nil {
+		return err
+	}
+	// partial results are never cached
+	if writeback && !call.Meta.Partial() {
+		rows, _ := q.PersistenceRows()
+		c.logger().Debugf("[%s] save query result to cache by index:%v, count:%d", "QueryGroupBy.CacheQuery", target, len(rows))
+		return c.GroupByCache.InsertBatch(target, rows, 0)
+	}
 	return nil
 }
 
@@ -214,13 +255,18 @@ func (q *QueryGroupBy) conditionFilterMD5() Condition {
 
 // QueryGroupBy special query for GroupBy type query
 func (c *Client) QueryGroupBy(query *QueryGroupBy, cacheIndex string, writeback bool) error {
+	return c.QueryGroupByContext(context.Background(), query, cacheIndex, writeback)
+}
+
+// QueryGroupByContext special query for GroupBy type query with context
+func (c *Client) QueryGroupByContext(ctx context.Context, query *QueryGroupBy, cacheIndex string, writeback bool) error {
 	c.logger().Debugf("[%s] starting query for `groupBy` query...", "Client.QueryGroupBy")
 	if c.GroupByCache == nil || cacheIndex == "" {
 		c.logger().Debugf("[%s] no GroupByCache or cacheIndex given", "Client.QueryGroupBy")
-		return c.Query(query)
+		return c.QueryContext(ctx, query)
 	}
 	c.logger().Debugf("[%s] try quering from cache by index:%v", "Client.QueryGroupBy", cacheIndex)
-	return query.CacheQuery(c, cacheIndex, writeback)
+	return query.CacheQueryContext(ctx, c, cacheIndex, writeback)
 }
 
 func jsonStr(data interface{}) string {
@@ -2,7 +2,7 @@ package godruid
 
 import (
 	"fmt"
-	"strings"
+	"sort"
 	"time"
 )
 
@@ -10,69 +10,153 @@ import (
 type IntervalSlot struct {
 	TimePos time.Time `json:"timePos"`
 	TimeLen int64     `json:"timeLen"`
+	// SubSecond the rest of the length under a second, for the edge slots of intervals with sub-second endpoints
+	SubSecond time.Duration `json:"subSecond,omitempty"`
 }
 
 // ToInterval to interval string
 func (i *IntervalSlot) ToInterval() string {
-	startTimeStr := i.TimePos.Format(time.RFC3339)
-	endTime := i.TimePos.Add(time.Duration(i.TimeLen) * time.Second)
-	endTimeStr := endTime.Format(time.RFC3339)
+	startTimeStr := i.TimePos.Format(time.RFC3339Nano)
+	endTimeStr := i.end().Format(time.RFC3339Nano)
 	return fmt.Sprintf("%s/%s", startTimeStr, endTimeStr)
 }
 
+// end the end of the slot
+func (i *IntervalSlot) end() time.Time {
+	return i.TimePos.Add(time.Duration(i.TimeLen)*time.Second + i.SubSecond)
+}
+
+// wholeSeconds whether the endpoints of the slot are of whole seconds, as the slots are keyed in GroupByCache
+func (i *IntervalSlot) wholeSeconds() bool {
+	return i.TimePos.Nanosecond() == 0 && i.SubSecond == 0
+}
+
+// newIntervalSlot slot of [start, end)
+func newIntervalSlot(start, end time.Time) IntervalSlot {
+	d := end.Sub(start)
+	return IntervalSlot{TimePos: start, TimeLen: int64(d / time.Second), SubSecond: d % time.Second}
+}
+
+// SlotStrategy distribute an interval into the slots queried and cached by QueryGroupBy.CacheQuery,
+// the slots must be sorted and exactly tile the interval.
+type SlotStrategy interface {
+	DistributeSlots(interval Interval) ([]IntervalSlot, error)
+}
+
+// TieredSlots slot strategy distributing intervals into the whole buckets of the largest tiers first,
+// the rest at the edges are distributed by the smaller tiers, and the rest of the smallest tier are slots as they are,
+// including the sub-second parts of the interval endpoints.
+type TieredSlots struct {
+	Tiers    []TimeUnit     // in any order
+	Location *time.Location // time zone of the buckets, the location of the interval start when nil
+}
+
+// DefaultSlotStrategy whole days and hours in the location of the interval start
+var DefaultSlotStrategy SlotStrategy = TieredSlots{Tiers: []TimeUnit{Day, Hour}}
+
+func (c *Client) slotStrategy() SlotStrategy {
+	if c.SlotStrategy == nil {
+		return DefaultSlotStrategy
+	}
+	return c.SlotStrategy
+}
+
+// DistributeSlots implement SlotStrategy
+func (s TieredSlots) DistributeSlots(interval Interval) ([]IntervalSlot, error) {
+	loc := s.Location
+	if loc == nil {
+		loc = interval.Start().Location()
+	}
+
+	tiers := append([]TimeUnit(nil), s.Tiers...)
+	sort.Slice(tiers, func(a, b int) bool { return tiers[a] > tiers[b] })
+	var bucketers []*granBucketer
+	for n, tier := range tiers {
+		if n > 0 && tier == tiers[n-1] {
+			continue
+		}
+		bucketers = append(bucketers, tier.bucketer(loc))
+	}
+	return distributeTiers(interval.Start().In(loc), interval.End().In(loc), bucketers), nil
+}
+
+// distributeTiers distribute [start, end) into the whole buckets of the first tier, and the edges by the rest tiers
+func distributeTiers(start, end time.Time, tiers []*granBucketer) []IntervalSlot {
+	if !start.Before(end) {
+		return nil
+	}
+	if len(tiers) == 0 {
+		return []IntervalSlot{newIntervalSlot(start, end)}
+	}
+
+	b := tiers[0]
+	first := b.floor(start)
+	if first.Before(start) {
+		first = b.next(first)
+	}
+	var whole []IntervalSlot
+	last := first
+	for next := b.next(last); !next.After(end); next = b.next(last) {
+		whole = append(whole, newIntervalSlot(last, next))
+		last = next
+	}
+	if len(whole) == 0 {
+		return distributeTiers(start, end, tiers[1:])
+	}
+
+	ret := distributeTiers(start, first, tiers[1:])
+	ret = append(ret, whole...)
+	return append(ret, distributeTiers(last, end, tiers[1:])...)
+}
+
+// checkSlots check the slots exactly tile the interval
+func checkSlots(interval Interval, slots []IntervalSlot) error {
+	pos := interval.Start()
+	for _, slot := range slots {
+		end := slot.end()
+		if !slot.TimePos.Equal(pos) || !end.After(pos) {
+			return fmt.Errorf("slot %s does not tile interval(%s)", slot.ToInterval(), interval)
+		}
+		pos = end
+	}
+	if !pos.Equal(interval.End()) {
+		return fmt.Errorf("slots end at %s before the end of interval(%s)", pos.Format(time.RFC3339Nano), interval)
+	}
+	return nil
+}
+
 // DistributeIntervalSlots split intervals to whole days and hours
 func (q *QueryGroupBy) DistributeIntervalSlots() ([]IntervalSlot, error) {
+	return q.DistributeIntervalSlotsBy(DefaultSlotStrategy)
+}
+
+// DistributeIntervalSlotsBy split intervals to slots by the strategy, an error is returned when the slots do not tile the intervals
+func (q *QueryGroupBy) DistributeIntervalSlotsBy(strategy SlotStrategy) ([]IntervalSlot, error) {
 	ret := []IntervalSlot{}
 	for _, i := range q.Intervals {
-		intervalSlots, err := DistributeIntervals(i)
+		interval, err := ParseISOInterval(i)
 		if err != nil {
 			return ret, err
 		}
-		for _, intervalSlot := range intervalSlots {
-			ret = append(ret, intervalSlot)
+		intervalSlots, err := strategy.DistributeSlots(interval)
+		if err != nil {
+			return ret, err
+		}
+		if err := checkSlots(interval, intervalSlots); err != nil {
+			return ret, err
 		}
+		ret = append(ret, intervalSlots...)
 	}
 	return ret, nil
 }
 
-// DistributeIntervals distribute interval to serval interval slots, minium to hour
+// DistributeIntervals distribute interval to serval interval slots of whole days and hours by DefaultSlotStrategy
 func DistributeIntervals(interval string) ([]IntervalSlot, error) {
-	ret := []IntervalSlot{}
-	intervalSlot, err := ParseInterval(interval)
-	if err == nil {
-		// TODO: 按年，月，周
-		dSi, dIs, dEi := DistributeDays(*intervalSlot)
-		if dSi != nil {
-			hSi, hIs, hEi := DistributeHours(*dSi)
-
-			if hSi != nil {
-				ret = append(ret, *hSi)
-			}
-			for _, hi := range hIs {
-				ret = append(ret, hi)
-			}
-			if hEi != nil {
-				ret = append(ret, *hEi)
-			}
-		}
-		for _, di := range dIs {
-			ret = append(ret, di)
-		}
-		if dEi != nil {
-			hSi, hIs, hEi := DistributeHours(*dEi)
-
-			if hSi != nil {
-				ret = append(ret, *hSi)
-			}
-			for _, hi := range hIs {
-				ret = append(ret, hi)
-			}
-			if hEi != nil {
-				ret = append(ret, *hEi)
-			}
-		}
+	i, err := ParseISOInterval(interval)
+	if err != nil {
+		return []IntervalSlot{}, err
 	}
-	return ret, err
+	return DefaultSlotStrategy.DistributeSlots(i)
 }
 
 // DistributeDays distribute interval to serval day interval slots
@@ -119,7 +203,7 @@ func DistributeHours(intervalSlot IntervalSlot) (*IntervalSlot, []IntervalSlot,
 	for ; intervalSlot.TimeLen-timePosRel >= int64(3600); timePosRel += int64(3600) {
 		daysTimeStarts = append(daysTimeStarts, IntervalSlot{
 			TimePos: intervalSlot.TimePos.Add(time.Duration(timePosRel) * time.Second),
-			TimeLen: int64(86400),
+			TimeLen: int64(3600),
 		})
 	}
 	if intervalSlot.TimeLen > timePosRel {
@@ -131,20 +215,12 @@ func DistributeHours(intervalSlot IntervalSlot) (*IntervalSlot, []IntervalSlot,
 	return startIntervalSlot, daysTimeStarts, endIntervalSlot
 }
 
+// ParseInterval parse the ISO-8601 interval to an interval slot, see ParseISOInterval for the supported forms
 func ParseInterval(interval string) (*IntervalSlot, error) {
-	timeRange := strings.SplitN(interval, "/", 2)
-	if len(timeRange) != 2 {
-		return nil, fmt.Errorf("interval(%s) format is invalid", interval)
-	}
-	startTime, err1 := time.Parse(time.RFC3339, timeRange[0])
-	if err1 != nil {
-		return nil, err1
-	}
-	endTime, err2 := time.Parse(time.RFC3339, timeRange[1])
-	if err2 != nil {
-		return nil, err2
+	i, err := ParseISOInterval(interval)
+	if err != nil {
+		return nil, err
 	}
-	timeLenNanoSec := endTime.Sub(startTime)
-	intervalSlot := IntervalSlot{TimePos: startTime, TimeLen: int64(timeLenNanoSec / time.Second)}
+	intervalSlot := IntervalSlot{TimePos: i.Start(), TimeLen: int64(i.Duration() / time.Second)}
 	return &intervalSlot, nil
 }
@@ -1,6 +1,8 @@
 package godruid
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -46,3 +48,199 @@ func Test_ParseInterval(t *testing.T) {
 		})
 	}
 }
+
+func slotIntervals(slots []IntervalSlot) []string {
+	ret := []string{}
+	for _, s := range slots {
+		ret = append(ret, s.ToInterval())
+	}
+	return ret
+}
+
+func TestDistributeIntervals(t *testing.T) {
+	got, err := DistributeIntervals("2019-01-01T22:30:00Z/2019-01-03T01:00:00Z")
+	if err != nil {
+		t.Fatalf("DistributeIntervals() error = %v", err)
+	}
+	want := []string{
+		"2019-01-01T22:30:00Z/2019-01-01T23:00:00Z",
+		"2019-01-01T23:00:00Z/2019-01-02T00:00:00Z",
+		"2019-01-02T00:00:00Z/2019-01-03T00:00:00Z",
+		"2019-01-03T00:00:00Z/2019-01-03T01:00:00Z",
+	}
+	if !reflect.DeepEqual(slotIntervals(got), want) {
+		t.Errorf("DistributeIntervals() = %v, want %v", slotIntervals(got), want)
+	}
+}
+
+func TestTieredSlots_DistributeSlots(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name     string
+		strategy TieredSlots
+		interval string
+		want     []string
+	}{
+		{
+			"local days",
+			TieredSlots{Tiers: []TimeUnit{Day, Hour}, Location: shanghai},
+			"2019-01-01T16:00:00Z/2019-01-03T16:00:00Z",
+			[]string{"2019-01-02T00:00:00+08:00/2019-01-03T00:00:00+08:00", "2019-01-03T00:00:00+08:00/2019-01-04T00:00:00+08:00"},
+		},
+		{
+			"calendar tiers",
+			TieredSlots{Tiers: []TimeUnit{Hour, Year, Month, Week, Day}},
+			"2019-12-30T22:00:00Z/2021-02-10T00:00:00Z",
+			[]string{
+				"2019-12-30T22:00:00Z/2019-12-30T23:00:00Z",
+				"2019-12-30T23:00:00Z/2019-12-31T00:00:00Z",
+				"2019-12-31T00:00:00Z/2020-01-01T00:00:00Z",
+				"2020-01-01T00:00:00Z/2021-01-01T00:00:00Z",
+				"2021-01-01T00:00:00Z/2021-02-01T00:00:00Z",
+				"2021-02-01T00:00:00Z/2021-02-08T00:00:00Z",
+				"2021-02-08T00:00:00Z/2021-02-09T00:00:00Z",
+				"2021-02-09T00:00:00Z/2021-02-10T00:00:00Z",
+			},
+		},
+		{
+			"minutes rest",
+			TieredSlots{Tiers: []TimeUnit{Hour, Minute}},
+			"2019-01-01T00:58:30Z/2019-01-01T02:01:00Z",
+			[]string{
+				"2019-01-01T00:58:30Z/2019-01-01T00:59:00Z",
+				"2019-01-01T00:59:00Z/2019-01-01T01:00:00Z",
+				"2019-01-01T01:00:00Z/2019-01-01T02:00:00Z",
+				"2019-01-01T02:00:00Z/2019-01-01T02:01:00Z",
+			},
+		},
+		{
+			"sub-second edges",
+			TieredSlots{Tiers: []TimeUnit{Hour}},
+			"2020-01-01T00:00:00.500Z/2020-01-01T02:00:00.250Z",
+			[]string{
+				"2020-01-01T00:00:00.5Z/2020-01-01T01:00:00Z",
+				"2020-01-01T01:00:00Z/2020-01-01T02:00:00Z",
+				"2020-01-01T02:00:00Z/2020-01-01T02:00:00.25Z",
+			},
+		},
+		{
+			"no tiers",
+			TieredSlots{},
+			"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z",
+			[]string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, _ := ParseISOInterval(tt.interval)
+			got, err := tt.strategy.DistributeSlots(i)
+			if err != nil {
+				t.Fatalf("TieredSlots.DistributeSlots() error = %v", err)
+			}
+			if !reflect.DeepEqual(slotIntervals(got), tt.want) {
+				t.Errorf("TieredSlots.DistributeSlots() = %v, want %v", slotIntervals(got), tt.want)
+			}
+			if err := checkSlots(i, got); err != nil {
+				t.Errorf("checkSlots() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTieredSlots_tiling(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	strategy := TieredSlots{Tiers: []TimeUnit{Year, Month, Week, Day, Hour, Minute}, Location: ny}
+	start := time.Date(2019, 12, 25, 7, 13, 21, 0, time.UTC)
+	for n := 0; n < 200; n++ {
+		s := start.Add(time.Duration(n*n*79) * time.Minute)
+		e := s.Add(time.Duration(n*104729+61) * time.Second)
+		i := NewInterval(s, e)
+		slots, err := strategy.DistributeSlots(i)
+		if err != nil {
+			t.Fatalf("TieredSlots.DistributeSlots(%s) error = %v", i, err)
+		}
+		if err := checkSlots(i, slots); err != nil {
+			t.Fatalf("TieredSlots.DistributeSlots(%s) = %v: %v", i, slotIntervals(slots), err)
+		}
+	}
+
+	far := NewInterval(time.Date(2600, 1, 1, 0, 30, 0, 0, time.UTC), time.Date(2600, 1, 1, 2, 0, 0, 0, time.UTC))
+	if slots, err := (TieredSlots{Tiers: []TimeUnit{Hour}}).DistributeSlots(far); err != nil || len(slots) != 2 {
+		t.Errorf("TieredSlots.DistributeSlots(%s) = %v, %v", far, slotIntervals(slots), err)
+	}
+
+	subSecond := NewInterval(start.Add(-time.Millisecond), start.Add(1500*time.Millisecond))
+	if slots, err := strategy.DistributeSlots(subSecond); err != nil || checkSlots(subSecond, slots) != nil {
+		t.Errorf("TieredSlots.DistributeSlots(%s) = %v, %v", subSecond, slotIntervals(slots), err)
+	}
+}
+
+func TestQueryGroupBy_DistributeIntervalSlots_toDate(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	q := &QueryGroupBy{Intervals: IntervalsOf(MonthToDate(shanghai))}
+	slots, err := q.DistributeIntervalSlots()
+	if err != nil {
+		t.Fatalf("QueryGroupBy.DistributeIntervalSlots() error = %v", err)
+	}
+	i, _ := ParseISOInterval(q.Intervals[0])
+	if err := checkSlots(i, slots); err != nil {
+		t.Errorf("QueryGroupBy.DistributeIntervalSlots() = %v: %v", slotIntervals(slots), err)
+	}
+}
+
+type testSlotStrategy struct{}
+
+func (testSlotStrategy) DistributeSlots(interval Interval) ([]IntervalSlot, error) {
+	return []IntervalSlot{newIntervalSlot(interval.Start(), interval.End().Add(-time.Hour))}, nil
+}
+
+func TestQueryGroupBy_DistributeIntervalSlotsBy(t *testing.T) {
+	q := &QueryGroupBy{Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+	if _, err := q.DistributeIntervalSlotsBy(testSlotStrategy{}); err == nil {
+		t.Errorf("QueryGroupBy.DistributeIntervalSlotsBy() error = nil for slots not tiling the interval")
+	}
+	slots, err := q.DistributeIntervalSlotsBy(TieredSlots{Tiers: []TimeUnit{Hour}})
+	if err != nil || len(slots) != 24 {
+		t.Errorf("QueryGroupBy.DistributeIntervalSlotsBy() = %v, %v", slotIntervals(slots), err)
+	}
+}
+
+func TestClient_SlotStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	cache := &testGroupByCache{}
+	client := &Client{Url: server.URL, HttpClient: server.Client(), GroupByCache: cache, SlotStrategy: TieredSlots{Tiers: []TimeUnit{Hour}}}
+	query := &QueryGroupBy{
+		Granularity:  GranAll,
+		Dimensions:   []DimSpec{"os"},
+		Aggregations: []Aggregation{*AggCount("count")},
+		Intervals:    []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"},
+	}
+	if err := query.CacheQuery(client, "t", false); err != nil {
+		t.Fatalf("QueryGroupBy.CacheQuery() error = %v", err)
+	}
+	if cache.selects != 24 {
+		t.Errorf("cache selects = %d, want 24", cache.selects)
+	}
+
+	cache.selects = 0
+	query.Intervals = []string{"2019-01-01T00:00:00.500Z/2019-01-01T02:00:00Z"}
+	if err := query.CacheQuery(client, "t", false); err != nil {
+		t.Fatalf("QueryGroupBy.CacheQuery() of sub-second interval error = %v", err)
+	}
+	if cache.selects != 1 {
+		t.Errorf("cache selects = %d, want 1 for the slot of whole seconds", cache.selects)
+	}
+}
@@ -41,24 +41,12 @@ func (q *QueryGroupBy) DimNames() []string {
 
 // AggNames query's aggregation output names
 func (q *QueryGroupBy) AggNames() []string {
-	ret := []string{}
-	for _, agg := range q.Aggregations {
-		if agg.Type == "filtered" {
-			ret = append(ret, agg.Aggregator.Name)
-		} else {
-			ret = append(ret, agg.Name)
-		}
-	}
-	return ret
+	return aggregationNames(q.Aggregations)
 }
 
 // PostAggNames query's post aggregation output names
 func (q *QueryGroupBy) PostAggNames() []string {
-	ret := []string{}
-	for _, pa := range q.PostAggregations {
-		ret = append(ret, pa.Name)
-	}
-	return ret
+	return postAggregationNames(q.PostAggregations)
 }
 
 func (q *QueryGroupBy) merge(oq *QueryGroupBy) {
@@ -67,8 +55,43 @@ func (q *QueryGroupBy) merge(oq *QueryGroupBy) {
 	// 重新生成json
 }
 
+// mergeIntervals set the union of the intervals to the query,
+// the intervals can not be parsed are appended as they are.
 func (q *QueryGroupBy) mergeIntervals(intervals Intervals) {
-	// TODO: 暂时不实现
+	set, err := ParseIntervalSet(q.Intervals)
+	oSet, oErr := ParseIntervalSet(intervals)
+	if err == nil && oErr == nil {
+		q.Intervals = set.Union(oSet).ToIntervals()
+		return
+	}
+
+	merged := append(Intervals{}, q.Intervals...)
+	for _, i := range intervals {
+		found := false
+		for _, mi := range merged {
+			if mi == i {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, i)
+		}
+	}
+	q.Intervals = merged
+}
+
+// intervalsOverlap whether the two intervals overlap, false when any of them can not be parsed
+func intervalsOverlap(intervals, oIntervals Intervals) bool {
+	set, err := ParseIntervalSet(intervals)
+	if err != nil {
+		return false
+	}
+	oSet, err := ParseIntervalSet(oIntervals)
+	if err != nil {
+		return false
+	}
+	return set.Overlaps(oSet)
 }
 
 func (q *QueryGroupBy) mergeQueryResult(oResult []GroupbyItem) {
@@ -144,7 +167,10 @@ func (q *QueryGroupBy) canMerge(oq *QueryGroupBy) error {
 			return errors.New("can not merge with same intervals")
 		}
 	}
-	if q.DataSource != oq.DataSource {
+	if q.QueryResult != nil && intervalsOverlap(q.Intervals, oq.Intervals) {
+		return errors.New("can not merge with overlapped intervals")
+	}
+	if !sameDataSource(q.DataSource, oq.DataSource) {
 		return errors.New("DataSource is not same")
 	}
 	if !reflect.DeepEqual(q.Context, oq.Context) {
@@ -191,21 +217,85 @@ func (q *QueryGroupBy) canMerge(oq *QueryGroupBy) error {
 }
 
 func (q *QueryGroupBy) aggTypes() []string {
+	return aggregationTypes(q.Aggregations)
+}
+
+func (q *QueryGroupBy) postAggExps() []string {
+	return postAggregationExps(q.PostAggregations)
+}
+
+func aggregationNames(aggs []Aggregation) []string {
 	ret := []string{}
-	for _, agg := range q.Aggregations {
+	for _, agg := range aggs {
+		if agg.Type == "filtered" {
+			ret = append(ret, agg.Aggregator.Name)
+		} else {
+			ret = append(ret, agg.Name)
+		}
+	}
+	return ret
+}
+
+func aggregationTypes(aggs []Aggregation) []string {
+	ret := []string{}
+	for _, agg := range aggs {
 		ret = append(ret, aggType(agg))
 	}
 	return ret
 }
 
-func (q *QueryGroupBy) postAggExps() []string {
+func postAggregationNames(postAggs []PostAggregation) []string {
+	ret := []string{}
+	for _, pa := range postAggs {
+		ret = append(ret, pa.Name)
+	}
+	return ret
+}
+
+func postAggregationExps(postAggs []PostAggregation) []string {
 	ret := []string{}
-	for _, pa := range q.PostAggregations {
+	for _, pa := range postAggs {
 		ret = append(ret, postAggExp(pa))
 	}
 	return ret
 }
 
+// canMergeAggs whether the results of the aggregations and post aggregations can be merged by mergeAgg and reComputePostAggs
+func canMergeAggs(aggs []Aggregation, postAggs []PostAggregation) bool {
+	for _, agg := range aggs {
+		switch aggType(agg) {
+		case "count", "longSum", "doubleSum", "min", "doubleMin", "longMin", "max", "doubleMax", "longMax":
+		default:
+			return false
+		}
+	}
+	for _, pa := range postAggs {
+		if !canComputePostAgg(pa) {
+			return false
+		}
+	}
+	return true
+}
+
+func canComputePostAgg(pg PostAggregation) bool {
+	switch pg.Type {
+	case "fieldAccess", "constant":
+		return true
+	case "arithmetic":
+		if len(pg.Fields) < 1 || len(pg.Fields) > 2 {
+			return false
+		}
+		for _, f := range pg.Fields {
+			if !canComputePostAgg(f) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
 func mergeAgg(aggType string, aggVals ...interface{}) interface{} {
 	if len(aggVals) == 0 {
 		return nil
@@ -188,7 +188,36 @@ func TestQueryGroupBy_canMerge(t *testing.T) {
 		oq      *QueryGroupBy
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			"adjacent",
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}, QueryResult: []GroupbyItem{}},
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-02T00:00:00Z/2019-01-03T00:00:00Z"}},
+			false,
+		},
+		{
+			"overlapped",
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}, QueryResult: []GroupbyItem{}},
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-01T12:00:00Z/2019-01-03T00:00:00Z"}},
+			true,
+		},
+		{
+			"overlapped without result",
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}},
+			&QueryGroupBy{Granularity: GranAll, Intervals: []string{"2019-01-01T12:00:00Z/2019-01-03T00:00:00Z"}},
+			false,
+		},
+		{
+			"datasource",
+			&QueryGroupBy{Granularity: GranAll, DataSource: DataSourceTable("wiki")},
+			&QueryGroupBy{Granularity: GranAll, DataSource: TableName("wiki")},
+			false,
+		},
+		{
+			"other datasource",
+			&QueryGroupBy{Granularity: GranAll, DataSource: DataSourceTable("wiki")},
+			&QueryGroupBy{Granularity: GranAll, DataSource: DataSourceUnion("wiki")},
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -199,6 +228,43 @@ func TestQueryGroupBy_canMerge(t *testing.T) {
 	}
 }
 
+func TestQueryGroupBy_mergeIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals Intervals
+		oInterval Intervals
+		want      Intervals
+	}{
+		{
+			"coalesce",
+			Intervals{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"},
+			Intervals{"2019-01-02T00:00:00Z/2019-01-03T00:00:00Z", "2019-01-05T00:00:00Z/2019-01-06T00:00:00Z"},
+			Intervals{"2019-01-01T00:00:00.000Z/2019-01-03T00:00:00.000Z", "2019-01-05T00:00:00.000Z/2019-01-06T00:00:00.000Z"},
+		},
+		{
+			"empty",
+			nil,
+			Intervals{"2019-01-01T00:00:00Z/P1D"},
+			Intervals{"2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z"},
+		},
+		{
+			"invalid",
+			Intervals{"2019-01-01T00:00:00/2019-01-02T00:00:00:00"},
+			Intervals{"2019-01-01T00:00:00/2019-01-02T00:00:00:00", "2019-01-01T00:00:00Z/P1D"},
+			Intervals{"2019-01-01T00:00:00/2019-01-02T00:00:00:00", "2019-01-01T00:00:00Z/P1D"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &QueryGroupBy{Intervals: tt.intervals}
+			q.mergeIntervals(tt.oInterval)
+			if !reflect.DeepEqual(q.Intervals, tt.want) {
+				t.Errorf("QueryGroupBy.mergeIntervals() = %v, want %v", q.Intervals, tt.want)
+			}
+		})
+	}
+}
+
 func TestQueryGroupBy_aggTypes(t *testing.T) {
 	tests := []struct {
 		name string
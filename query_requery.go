@@ -0,0 +1,140 @@
+package godruid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Partial whether the result of the response is partial, for missing segments or uncovered intervals.
+func (m *ResponseMeta) Partial() bool {
+	if m == nil {
+		return false
+	}
+	return len(m.Context.MissingSegments) > 0 || len(m.Context.UncoveredIntervals) > 0 || m.Context.UncoveredIntervalsOverflowed
+}
+
+// canRequery whether the partial result of the query can be completed by requery
+func canRequery(query Query) bool {
+	switch q := query.(type) {
+	case *QueryGroupBy:
+		// ! only for granularities `all`, the same as Merge
+		return q.Granularity == GranAll && canMergeAggs(q.Aggregations, q.PostAggregations)
+	case *QueryTimeseries:
+		return canMergeAggs(q.Aggregations, q.PostAggregations)
+	default:
+		return false
+	}
+}
+
+// SegmentsQuery the query restricted to the segments, its intervals are replaced by a `segments` interval spec
+// when it is serialized. It is the Query of the calls requerying missing segments, see Client.RequeryPartial.
+type SegmentsQuery struct {
+	Query
+	Segments []SegmentDescriptor
+}
+
+// MarshalJSON serialize the query with the segments spec as its intervals
+func (q *SegmentsQuery) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(q.Query)
+	if err != nil {
+		return nil, err
+	}
+	var query map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&query); err != nil {
+		return nil, err
+	}
+	query["intervals"] = map[string]interface{}{"type": "segments", "segments": q.Segments}
+	return json.Marshal(query)
+}
+
+// requery re-issue the query of the partial response for only its missing segments, and merge the results into the query.
+// Missing segments are queried by a SegmentsQuery, so the data of the segments already in the result
+// is not counted twice. Uncovered intervals are not requeried, as no segment covers them.
+// The missing segments of call.Meta are replaced by those of the requery, and the result is saved into
+// ResultCache once it is complete.
+func (c *Client) requery(ctx context.Context, call *Call) error {
+	if !canRequery(call.Query) || call.Meta == nil || len(call.Meta.Context.MissingSegments) == 0 {
+		return nil
+	}
+
+	reCall := &Call{Query: &SegmentsQuery{Query: requeryClone(call.Query), Segments: call.Meta.Context.MissingSegments}}
+	if err := reCall.Encode(); err != nil {
+		return err
+	}
+	c.logger().Debugf("[%s] requery partial result of query %s", "Client.Query", call.QueryID)
+	if err := c.roundTrip(ctx, reCall, c.sendCall); err != nil {
+		return err
+	}
+	result, err := mergeRequeryResult(call.Query, reCall.Result)
+	if err != nil {
+		return err
+	}
+	call.Meta.Context.MissingSegments = nil
+	if reCall.Meta != nil {
+		call.Meta.Context.MissingSegments = reCall.Meta.Context.MissingSegments
+	}
+	if call.cacheChecked {
+		c.saveResult(call, result)
+	}
+	return nil
+}
+
+// requeryClone clone the query without its result, the query id is dropped for a new one.
+func requeryClone(query Query) Query {
+	clone := cloneQuery(query)
+	switch q := clone.(type) {
+	case *QueryGroupBy:
+		q.Context = withoutQueryID(q.Context)
+	case *QueryTimeseries:
+		q.Context = withoutQueryID(q.Context)
+	}
+	return clone
+}
+
+// withoutQueryID copy of the query context without the query id
+func withoutQueryID(queryCtx map[string]interface{}) map[string]interface{} {
+	if queryCtx == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(queryCtx))
+	for k, v := range queryCtx {
+		if k != QUERYID {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// mergeRequeryResult merge the requery result into the query, and return the merged result which is the RawJSON of the query as well
+func mergeRequeryResult(query Query, result []byte) ([]byte, error) {
+	switch q := query.(type) {
+	case *QueryGroupBy:
+		var items []GroupbyItem
+		if err := json.Unmarshal(result, &items); err != nil {
+			return nil, err
+		}
+		q.mergeQueryResult(items)
+		raw, err := json.Marshal(q.QueryResult)
+		if err != nil {
+			return nil, err
+		}
+		q.RawJSON = raw
+		return raw, nil
+	case *QueryTimeseries:
+		var items []Timeseries
+		if err := json.Unmarshal(result, &items); err != nil {
+			return nil, err
+		}
+		q.mergeQueryResult(items)
+		raw, err := json.Marshal(q.QueryResult)
+		if err != nil {
+			return nil, err
+		}
+		q.RawJSON = raw
+		return raw, nil
+	}
+	return result, nil
+}
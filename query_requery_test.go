@@ -0,0 +1,185 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newRequeryServer(t *testing.T, first, partialContext, requery, requeryContext string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Intervals json.RawMessage `json:"intervals"`
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+		if string(query.Intervals) == `["2019-01-01T00:00:00Z/2019-01-03T00:00:00Z"]` {
+			w.Header().Set(HeaderResponseContext, partialContext)
+			w.Write([]byte(first))
+			return
+		}
+		t.Logf("requery intervals: %s", query.Intervals)
+		if requeryContext != "" {
+			w.Header().Set(HeaderResponseContext, requeryContext)
+		}
+		w.Write([]byte(requery))
+	}))
+}
+
+func TestClient_RequeryPartial(t *testing.T) {
+	intervals := []string{"2019-01-01T00:00:00Z/2019-01-03T00:00:00Z"}
+
+	t.Run("timeseries missing segments", func(t *testing.T) {
+		server := newRequeryServer(t,
+			`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}},{"timestamp":"2019-01-02T00:00:00.000Z","result":{"count":1}}]`,
+			`{"missingSegments":[{"itvl":"2019-01-02T00:00:00.000Z/2019-01-03T00:00:00.000Z","ver":"v1","part":0}]}`,
+			`[{"timestamp":"2019-01-02T00:00:00.000Z","result":{"count":2}}]`,
+			"",
+		)
+		defer server.Close()
+
+		cache := testResultCache{}
+		client := &Client{Url: server.URL, HttpClient: server.Client(), ResultCache: cache, RequeryPartial: true, MissingSegmentsAsError: true}
+		var queries []Query
+		client.Use(func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, call *Call) error {
+				queries = append(queries, call.Query)
+				return next(ctx, call)
+			}
+		})
+		query := &QueryTimeseries{
+			Granularity:      GranDay,
+			Aggregations:     []Aggregation{*AggCount("count")},
+			PostAggregations: []PostAggregation{PostAggRawJson(`{"type":"fieldAccess","name":"total","fieldName":"count"}`)},
+			Intervals:        intervals,
+		}
+		meta, err := client.QueryMeta(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Client.QueryMeta() error = %v", err)
+		}
+		if meta.Partial() {
+			t.Errorf("ResponseMeta.Partial() = true after requery, want false")
+		}
+		want := []Timeseries{
+			{Timestamp: "2019-01-01T00:00:00.000Z", Result: map[string]interface{}{"count": 1.0, "total": 1.0}},
+			{Timestamp: "2019-01-02T00:00:00.000Z", Result: map[string]interface{}{"count": 3.0, "total": 3.0}},
+		}
+		if !reflect.DeepEqual(query.QueryResult, want) {
+			t.Errorf("Client.QueryMeta() result = %v, want %v", query.QueryResult, want)
+		}
+		var raw []Timeseries
+		if err := json.Unmarshal(query.RawJSON, &raw); err != nil || !reflect.DeepEqual(raw, want) {
+			t.Errorf("Client.QueryMeta() raw result = %s, want the merged result", query.RawJSON)
+		}
+		if len(queries) != 2 {
+			t.Fatalf("middleware saw %d calls, want the query and its requery", len(queries))
+		}
+		if requery, ok := queries[1].(*SegmentsQuery); !ok || len(requery.Segments) != 1 || requery.Query == Query(query) {
+			t.Errorf("requery call query = %#v, want a clone for the missing segment", queries[1])
+		}
+		if len(cache) != 1 {
+			t.Fatalf("completed result is not cached: %v", cache)
+		}
+
+		cachedQuery := &QueryTimeseries{
+			Granularity:      GranDay,
+			Aggregations:     []Aggregation{*AggCount("count")},
+			PostAggregations: []PostAggregation{PostAggRawJson(`{"type":"fieldAccess","name":"total","fieldName":"count"}`)},
+			Intervals:        intervals,
+		}
+		if err := client.Query(cachedQuery); err != nil {
+			t.Fatalf("Client.Query() error = %v", err)
+		}
+		if !reflect.DeepEqual(cachedQuery.QueryResult, want) {
+			t.Errorf("Client.Query() cached result = %v, want %v", cachedQuery.QueryResult, want)
+		}
+	})
+
+	t.Run("groupBy missing segments", func(t *testing.T) {
+		server := newRequeryServer(t,
+			`[{"version":"v1","timestamp":"2019-01-01T00:00:00.000Z","event":{"os":"a","count":1}}]`,
+			`{"missingSegments":[{"itvl":"2019-01-02T00:00:00.000Z/2019-01-03T00:00:00.000Z","ver":"v1","part":0}]}`,
+			`[{"version":"v1","timestamp":"2019-01-02T00:00:00.000Z","event":{"os":"a","count":2}},{"version":"v1","timestamp":"2019-01-02T00:00:00.000Z","event":{"os":"b","count":1}}]`,
+			"",
+		)
+		defer server.Close()
+
+		client := &Client{Url: server.URL, HttpClient: server.Client(), RequeryPartial: true}
+		query := &QueryGroupBy{
+			Granularity:  GranAll,
+			Dimensions:   []DimSpec{"os"},
+			Aggregations: []Aggregation{*AggCount("count")},
+			Intervals:    intervals,
+		}
+		meta, err := client.QueryMeta(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Client.QueryMeta() error = %v", err)
+		}
+		if meta.Partial() {
+			t.Errorf("ResponseMeta.Partial() = true after requery, want false")
+		}
+		got := map[string]interface{}{}
+		for _, item := range query.QueryResult {
+			got[item.Event["os"].(string)] = item.Event["count"]
+		}
+		if want := map[string]interface{}{"a": 3.0, "b": 1.0}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Client.QueryMeta() result = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("partial requery", func(t *testing.T) {
+		missing := `{"missingSegments":[{"itvl":"2019-01-02T00:00:00.000Z/2019-01-03T00:00:00.000Z","ver":"v1","part":0}]}`
+		server := newRequeryServer(t,
+			`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`,
+			missing,
+			`[]`,
+			missing,
+		)
+		defer server.Close()
+
+		cache := testResultCache{}
+		client := &Client{Url: server.URL, HttpClient: server.Client(), ResultCache: cache, RequeryPartial: true}
+		query := &QueryTimeseries{Granularity: GranAll, Aggregations: []Aggregation{*AggCount("count")}, Intervals: intervals}
+		meta, err := client.QueryMeta(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Client.QueryMeta() error = %v", err)
+		}
+		if !meta.Partial() || len(meta.Context.MissingSegments) != 1 {
+			t.Errorf("ResponseMeta.Context = %+v, want the segments missed by the requery", meta.Context)
+		}
+		if len(cache) != 0 {
+			t.Errorf("partial result is cached: %v", cache)
+		}
+	})
+
+	t.Run("uncovered intervals", func(t *testing.T) {
+		server := newRequeryServer(t,
+			`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`,
+			`{"uncoveredIntervals":["2019-01-02T00:00:00.000Z/2019-01-03T00:00:00.000Z"],"uncoveredIntervalsOverflowed":false}`,
+			`[{"timestamp":"2019-01-02T00:00:00.000Z","result":{"count":2}}]`,
+			"",
+		)
+		defer server.Close()
+
+		client := &Client{Url: server.URL, HttpClient: server.Client(), RequeryPartial: true}
+		query := &QueryTimeseries{
+			Granularity:  GranAll,
+			Aggregations: []Aggregation{*AggCount("count")},
+			Intervals:    intervals,
+			Context:      map[string]interface{}{UNCOVEREDINTERVALSLIMIT: 10},
+		}
+		meta, err := client.QueryMeta(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Client.QueryMeta() error = %v", err)
+		}
+		if !meta.Partial() {
+			t.Errorf("ResponseMeta.Partial() = false, want true for uncovered intervals")
+		}
+		want := []Timeseries{{Timestamp: "2019-01-01T00:00:00.000Z", Result: map[string]interface{}{"count": 1.0}}}
+		if !reflect.DeepEqual(query.QueryResult, want) {
+			t.Errorf("Client.QueryMeta() result = %v, want %v, uncovered intervals are not requeried", query.QueryResult, want)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ScanIterator iterate the scan query result batch by batch, without holding the whole response in memory.
+type ScanIterator interface {
+	// Next decode the next batch, returns false when there is no more batch or an error occurred.
+	Next() bool
+	// Batch the batch decoded by the last Next call.
+	Batch() *ScanBatch
+	// Err the error occurred during iterating.
+	Err() error
+	// Close release the response, the query is canceled on druid when it is not read to the end.
+	Close() error
+}
+
+// ScanBatch a batch of scan query result
+type ScanBatch struct {
+	SegmentID string   `json:"segmentId"`
+	Columns   []string `json:"columns"`
+	// Events rows of `list` result format
+	Events []map[string]interface{} `json:"-"`
+	// CompactedEvents rows of `compactedList` result format, values are in order of Columns
+	CompactedEvents [][]interface{} `json:"-"`
+}
+
+// Len rows count of the batch
+func (b *ScanBatch) Len() int {
+	if b.CompactedEvents != nil {
+		return len(b.CompactedEvents)
+	}
+	return len(b.Events)
+}
+
+// Event the i-th row as a map from column name to value, for both result formats.
+func (b *ScanBatch) Event(i int) map[string]interface{} {
+	if b.CompactedEvents == nil {
+		return b.Events[i]
+	}
+	event := map[string]interface{}{}
+	for j, v := range b.CompactedEvents[i] {
+		if j < len(b.Columns) {
+			event[b.Columns[j]] = v
+		}
+	}
+	return event
+}
+
+type scanListBatch struct {
+	SegmentID string                   `json:"segmentId"`
+	Columns   []string                 `json:"columns"`
+	Events    []map[string]interface{} `json:"events"`
+}
+
+type scanCompactedBatch struct {
+	SegmentID string          `json:"segmentId"`
+	Columns   []string        `json:"columns"`
+	Events    [][]interface{} `json:"events"`
+}
+
+// Stream query druid with the scan query and decode the result in a streaming way,
+// the caller must Close the returned iterator.
+func (c *Client) Stream(ctx context.Context, query *QueryScan) (ScanIterator, error) {
+	query.Setup()
+	setDataSource(query, c.DataSource)
+	reqJson, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger().Debugf("[%s] starting streaming scan query...", "Client.Stream")
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, SpanStream, reqJson)
+	call := &Call{Query: query, Request: reqJson}
+	if err := c.roundTrip(ctx, call, c.openCall); err != nil {
+		c.endSpan(span, call, err)
+		c.observeQuery(ctx, call, start, 0, err)
+		return nil, err
+	}
+
+	it := &scanIterator{
+		client:    c,
+		ctx:       ctx,
+		call:      call,
+		span:      span,
+		start:     start,
+		queryID:   call.QueryID,
+		resp:      call.Response,
+		decoder:   json.NewDecoder(call.Response.Body),
+		compacted: query.ResultFormat == ScanResultFormatCompactedList,
+	}
+	if err := it.expectDelim('['); err != nil {
+		it.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+type scanIterator struct {
+	client    *Client
+	ctx       context.Context
+	call      *Call
+	span      Span
+	start     time.Time
+	rows      int
+	queryID   string
+	resp      *http.Response
+	decoder   *json.Decoder
+	compacted bool
+	batch     *ScanBatch
+	err       error
+	done      bool
+	closed    bool
+}
+
+func (it *scanIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	if !it.decoder.More() {
+		it.done = true
+		it.batch = nil
+		it.err = it.expectDelim(']')
+		return false
+	}
+
+	if it.compacted {
+		var b scanCompactedBatch
+		if it.err = it.decoder.Decode(&b); it.err != nil {
+			return false
+		}
+		it.batch = &ScanBatch{SegmentID: b.SegmentID, Columns: b.Columns, CompactedEvents: b.Events}
+		if it.batch.CompactedEvents == nil {
+			it.batch.CompactedEvents = [][]interface{}{}
+		}
+	} else {
+		var b scanListBatch
+		if it.err = it.decoder.Decode(&b); it.err != nil {
+			return false
+		}
+		it.batch = &ScanBatch{SegmentID: b.SegmentID, Columns: b.Columns, Events: b.Events}
+	}
+	it.rows += it.batch.Len()
+	return true
+}
+
+func (it *scanIterator) Batch() *ScanBatch { return it.batch }
+
+func (it *scanIterator) Err() error {
+	if it.err != nil && it.ctx.Err() != nil {
+		return it.ctx.Err()
+	}
+	return it.err
+}
+
+func (it *scanIterator) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+	if !it.done || it.err != nil {
+		it.client.cancelDetached(nativeAPI, it.queryID)
+	}
+	it.done = true
+	err := it.resp.Body.Close()
+	it.span.SetAttribute("druid.rows", it.rows)
+	it.client.endSpan(it.span, it.call, it.Err())
+	it.client.observeQuery(it.ctx, it.call, it.start, it.rows, it.Err())
+	return err
+}
+
+func (it *scanIterator) expectDelim(delim json.Delim) error {
+	err := expectDelim(it.decoder, delim)
+	if err == io.EOF && delim == '[' {
+		return fmt.Errorf("empty scan query response")
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Stream(t *testing.T) {
+	tests := []struct {
+		name         string
+		resultFormat string
+		body         string
+		want         []map[string]interface{}
+		wantErr      bool
+	}{
+		{
+			"list",
+			ScanResultFormatList,
+			`[{"segmentId":"s1","columns":["a","b"],"events":[{"a":"x","b":1},{"a":"y","b":2}]},
+			  {"segmentId":"s2","columns":["a","b"],"events":[{"a":"z","b":3}]}]`,
+			[]map[string]interface{}{{"a": "x", "b": 1.0}, {"a": "y", "b": 2.0}, {"a": "z", "b": 3.0}},
+			false,
+		},
+		{
+			"compactedList",
+			ScanResultFormatCompactedList,
+			`[{"segmentId":"s1","columns":["a","b"],"events":[["x",1],["y",2]]}]`,
+			[]map[string]interface{}{{"a": "x", "b": 1.0}, {"a": "y", "b": 2.0}},
+			false,
+		},
+		{"empty", "", `[]`, nil, false},
+		{"truncated", "", `[{"segmentId":"s1","columns":["a"],"events":[{"a":"x"}]},{"segm`, []map[string]interface{}{{"a": "x"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := &Client{Url: server.URL, HttpClient: server.Client()}
+			it, err := client.Stream(context.Background(), &QueryScan{ResultFormat: tt.resultFormat})
+			if err != nil {
+				t.Fatalf("Client.Stream() error = %v", err)
+			}
+			defer it.Close()
+
+			var got []map[string]interface{}
+			for it.Next() {
+				batch := it.Batch()
+				for i := 0; i < batch.Len(); i++ {
+					got = append(got, batch.Event(i))
+				}
+			}
+			if (it.Err() != nil) != tt.wantErr {
+				t.Errorf("ScanIterator.Err() = %v, wantErr %v", it.Err(), tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanIterator events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package godruid
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLResultFormat result format of druid sql query
+type SQLResultFormat string
+
+const (
+	SQLResultObject      SQLResultFormat = "object"
+	SQLResultArray       SQLResultFormat = "array"
+	SQLResultObjectLines SQLResultFormat = "objectLines"
+	SQLResultArrayLines  SQLResultFormat = "arrayLines"
+	SQLResultCSV         SQLResultFormat = "csv"
+)
+
+// SQLQuery druid sql query, posted to `/druid/v2/sql`
+type SQLQuery struct {
+	Query          string                 `json:"query"`
+	ResultFormat   SQLResultFormat        `json:"resultFormat,omitempty"`
+	Header         bool                   `json:"header,omitempty"`
+	TypesHeader    bool                   `json:"typesHeader,omitempty"`
+	SQLTypesHeader bool                   `json:"sqlTypesHeader,omitempty"`
+	Parameters     []SQLParameter         `json:"parameters,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+}
+
+// SQLParameter dynamic parameter of sql query, bound to `?` in order
+type SQLParameter struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// SQLParam new sql parameter with sql type inferred from the go value
+func SQLParam(value interface{}) SQLParameter {
+	switch v := value.(type) {
+	case string:
+		return SQLParameter{Type: "VARCHAR", Value: v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return SQLParameter{Type: "BIGINT", Value: v}
+	case float32:
+		return SQLParameter{Type: "FLOAT", Value: v}
+	case float64:
+		return SQLParameter{Type: "DOUBLE", Value: v}
+	case bool:
+		return SQLParameter{Type: "BOOLEAN", Value: v}
+	case time.Time:
+		return SQLParameter{Type: "TIMESTAMP", Value: v.UTC().Format("2006-01-02 15:04:05.000")}
+	default:
+		return SQLParameter{Type: "OTHER", Value: v}
+	}
+}
+
+// SQLRow a row of sql query result, values are in order of SQLResult.Columns
+type SQLRow []interface{}
+
+// SQLResult sql query result
+type SQLResult struct {
+	Columns []string
+	// Types druid runtime types of columns, given when TypesHeader is set
+	Types []string
+	// SQLTypes sql types of columns, given when SQLTypesHeader is set
+	SQLTypes []string
+	Rows     []SQLRow
+}
+
+// Row the i-th row as a map from column name to value
+func (r *SQLResult) Row(i int) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for j, v := range r.Rows[i] {
+		if j < len(r.Columns) {
+			ret[r.Columns[j]] = v
+		}
+	}
+	return ret
+}
+
+// Decode decode the rows into dest, a pointer to a slice of structs or maps.
+// Columns are matched with the fields by their json names, the same as json.Unmarshal,
+// so TIMESTAMP columns in ISO-8601 format can be decoded into time.Time fields.
+func (r *SQLResult) Decode(dest interface{}) error {
+	rows := make([]map[string]interface{}, 0, len(r.Rows))
+	for i := range r.Rows {
+		rows = append(rows, r.Row(i))
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// QuerySQL query druid with sql, values of the result rows are typed by the column types when known,
+// otherwise json numbers are decoded as int64 or float64 and csv values are kept as string.
+// The rows are decoded as the response body is read, the body is not held in memory.
+func (c *Client) QuerySQL(ctx context.Context, query *SQLQuery) (*SQLResult, error) {
+	reqJson, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger().Debugf("[%s] starting sql query...", "Client.QuerySQL")
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, SpanSQL, nil)
+	call := &Call{SQL: query, Request: reqJson}
+	err = c.roundTrip(ctx, call, c.openCall)
+	var result *SQLResult
+	if err == nil {
+		result, err = c.decodeSQLResponse(ctx, call)
+	}
+	c.endSpan(span, call, err)
+	rows := 0
+	if result != nil {
+		rows = len(result.Rows)
+	}
+	c.observeQuery(ctx, call, start, rows, err)
+	return result, err
+}
+
+// decodeSQLResponse decode the result from the unread response body of the call and close it,
+// the body is copied for debugging only when debug data is recorded.
+func (c *Client) decodeSQLResponse(ctx context.Context, call *Call) (*SQLResult, error) {
+	defer call.Response.Body.Close()
+	body := io.Reader(call.Response.Body)
+	var debugBody *bytes.Buffer
+	if debugInfoFrom(ctx) != nil {
+		debugBody = &bytes.Buffer{}
+		body = io.TeeReader(body, debugBody)
+	}
+
+	_, span := c.tracer().Start(ctx, SpanDecode)
+	result, err := decodeSQLResult(call.SQL, body)
+	span.End(err)
+	if debugBody != nil {
+		c.debugResponse(ctx, call.Response.StatusCode, debugBody.Bytes())
+	}
+	if err != nil {
+		c.cancelOnDone(ctx, sqlAPI, call.QueryID)
+	}
+	return result, err
+}
+
+func decodeSQLResult(query *SQLQuery, body io.Reader) (*SQLResult, error) {
+	switch query.ResultFormat {
+	case "", SQLResultObject, SQLResultObjectLines:
+		records, err := decodeSQLRecords(body, query.ResultFormat == SQLResultObjectLines, decodeOrderedObject)
+		if err != nil {
+			return nil, err
+		}
+		result := sqlResultFromObjects(query, records)
+		result.typeJSONRows()
+		return result, nil
+	case SQLResultArray, SQLResultArrayLines:
+		records, err := decodeSQLRecords(body, query.ResultFormat == SQLResultArrayLines, decodeArray)
+		if err != nil {
+			return nil, err
+		}
+		result := sqlResultFromArrays(query, records)
+		result.typeJSONRows()
+		return result, nil
+	case SQLResultCSV:
+		reader := csv.NewReader(body)
+		reader.FieldsPerRecord = -1
+		lines, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		records := []sqlRecord{}
+		for _, line := range lines {
+			values := []interface{}{}
+			for _, v := range line {
+				values = append(values, v)
+			}
+			records = append(records, sqlRecord{values: values})
+		}
+		result := sqlResultFromArrays(query, records)
+		result.typeCSVRows()
+		return result, nil
+	default:
+		return nil, fmt.Errorf("not support sql result format: %s", query.ResultFormat)
+	}
+}
+
+// sqlRecord a decoded record of sql result, keys is only for object formats
+type sqlRecord struct {
+	keys   []string
+	values []interface{}
+}
+
+// decodeSQLRecords decode a json array of records, or newline delimited records when lines.
+func decodeSQLRecords(body io.Reader, lines bool, decodeRecord func(*json.Decoder) (sqlRecord, error)) ([]sqlRecord, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if !lines {
+		if err := expectDelim(decoder, '['); err != nil {
+			return nil, err
+		}
+	}
+
+	records := []sqlRecord{}
+	for decoder.More() {
+		record, err := decodeRecord(decoder)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	if !lines {
+		if err := expectDelim(decoder, ']'); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// decodeOrderedObject decode a json object with keys in order
+func decodeOrderedObject(decoder *json.Decoder) (sqlRecord, error) {
+	record := sqlRecord{}
+	if err := expectDelim(decoder, '{'); err != nil {
+		return record, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return record, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return record, fmt.Errorf("unexpected token %v in sql result, want object key", token)
+		}
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return record, err
+		}
+		record.keys = append(record.keys, key)
+		record.values = append(record.values, value)
+	}
+	return record, expectDelim(decoder, '}')
+}
+
+func decodeArray(decoder *json.Decoder) (sqlRecord, error) {
+	var values []interface{}
+	err := decoder.Decode(&values)
+	return sqlRecord{values: values}, err
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("unexpected token %v, want %v", token, delim)
+	}
+	return nil
+}
+
+func sqlResultFromObjects(query *SQLQuery, records []sqlRecord) *SQLResult {
+	result := &SQLResult{Rows: []SQLRow{}}
+	if query.Header && len(records) > 0 {
+		header := records[0]
+		records = records[1:]
+		result.Columns = header.keys
+		for _, v := range header.values {
+			typeHeader, _ := v.(map[string]interface{})
+			if query.TypesHeader {
+				t, _ := typeHeader["type"].(string)
+				result.Types = append(result.Types, t)
+			}
+			if query.SQLTypesHeader {
+				t, _ := typeHeader["sqlType"].(string)
+				result.SQLTypes = append(result.SQLTypes, t)
+			}
+		}
+	} else if len(records) > 0 {
+		result.Columns = records[0].keys
+	}
+
+	columnIndex := map[string]int{}
+	for i, col := range result.Columns {
+		columnIndex[col] = i
+	}
+	for _, record := range records {
+		row := make(SQLRow, len(result.Columns))
+		for i, k := range record.keys {
+			if j, ok := columnIndex[k]; ok {
+				row[j] = record.values[i]
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
+
+func sqlResultFromArrays(query *SQLQuery, records []sqlRecord) *SQLResult {
+	result := &SQLResult{Rows: []SQLRow{}}
+	headers := []*[]string{}
+	if query.Header {
+		headers = append(headers, &result.Columns)
+		if query.TypesHeader {
+			headers = append(headers, &result.Types)
+		}
+		if query.SQLTypesHeader {
+			headers = append(headers, &result.SQLTypes)
+		}
+	}
+	for _, header := range headers {
+		if len(records) == 0 {
+			break
+		}
+		for _, v := range records[0].values {
+			s, _ := v.(string)
+			*header = append(*header, s)
+		}
+		records = records[1:]
+	}
+
+	for _, record := range records {
+		result.Rows = append(result.Rows, SQLRow(record.values))
+	}
+	return result
+}
+
+// typeJSONRows convert json numbers by column types, numbers of unknown type are converted to int64 or float64
+func (r *SQLResult) typeJSONRows() {
+	for _, row := range r.Rows {
+		for i, v := range row {
+			row[i] = jsonTypedValue(v, r.columnType(i))
+		}
+	}
+}
+
+// typeCSVRows convert csv strings by column types, values keep as string when they can not be converted
+func (r *SQLResult) typeCSVRows() {
+	for _, row := range r.Rows {
+		for i, v := range row {
+			row[i] = csvTypedValue(v.(string), r.columnType(i))
+		}
+	}
+}
+
+// columnType druid runtime type of the i-th column, inferred from sql type when the runtime type is unknown
+func (r *SQLResult) columnType(i int) string {
+	if i < len(r.Types) && r.Types[i] != "" {
+		return r.Types[i]
+	}
+	if i >= len(r.SQLTypes) {
+		return ""
+	}
+	switch r.SQLTypes[i] {
+	case "BIGINT", "INTEGER", "SMALLINT", "TINYINT":
+		return "LONG"
+	case "FLOAT", "REAL":
+		return "FLOAT"
+	case "DOUBLE", "DECIMAL":
+		return "DOUBLE"
+	case "BOOLEAN":
+		return "BOOLEAN"
+	case "CHAR", "VARCHAR", "TIMESTAMP", "DATE":
+		return "STRING"
+	default:
+		return ""
+	}
+}
+
+func jsonTypedValue(v interface{}, columnType string) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if columnType != "FLOAT" && columnType != "DOUBLE" {
+			if i, err := value.Int64(); err == nil {
+				return i
+			}
+		}
+		f, _ := value.Float64()
+		return f
+	case []interface{}:
+		for i := range value {
+			value[i] = jsonTypedValue(value[i], "")
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+func csvTypedValue(value string, columnType string) interface{} {
+	switch columnType {
+	case "LONG", "FLOAT", "DOUBLE", "BOOLEAN":
+		if value == "" {
+			return nil
+		}
+	}
+
+	var typed interface{}
+	var err error
+	switch columnType {
+	case "LONG":
+		typed, err = strconv.ParseInt(value, 10, 64)
+	case "FLOAT", "DOUBLE":
+		typed, err = strconv.ParseFloat(value, 64)
+	case "BOOLEAN":
+		typed, err = strconv.ParseBool(strings.ToLower(value))
+	default:
+		return value
+	}
+	if err != nil {
+		return value
+	}
+	return typed
+}
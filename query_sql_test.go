@@ -0,0 +1,145 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_decodeSQLResult(t *testing.T) {
+	tests := []struct {
+		name  string
+		query SQLQuery
+		body  string
+		want  *SQLResult
+	}{
+		{
+			"object",
+			SQLQuery{},
+			`[{"b":"x","a":1},{"b":"y","a":2.5}]`,
+			&SQLResult{Columns: []string{"b", "a"}, Rows: []SQLRow{{"x", int64(1)}, {"y", 2.5}}},
+		},
+		{
+			"object-header-types",
+			SQLQuery{ResultFormat: SQLResultObject, Header: true, TypesHeader: true, SQLTypesHeader: true},
+			`[{"b":{"type":"STRING","sqlType":"VARCHAR"},"a":{"type":"DOUBLE","sqlType":"DOUBLE"}},{"b":"x","a":1}]`,
+			&SQLResult{
+				Columns:  []string{"b", "a"},
+				Types:    []string{"STRING", "DOUBLE"},
+				SQLTypes: []string{"VARCHAR", "DOUBLE"},
+				Rows:     []SQLRow{{"x", 1.0}},
+			},
+		},
+		{
+			"array-header",
+			SQLQuery{ResultFormat: SQLResultArray, Header: true},
+			`[["b","a"],["x",1],["y",null]]`,
+			&SQLResult{Columns: []string{"b", "a"}, Rows: []SQLRow{{"x", int64(1)}, {"y", nil}}},
+		},
+		{
+			"objectLines",
+			SQLQuery{ResultFormat: SQLResultObjectLines},
+			"{\"a\":1,\"tags\":[\"t1\",2]}\n{\"a\":2,\"tags\":[]}\n\n",
+			&SQLResult{Columns: []string{"a", "tags"}, Rows: []SQLRow{{int64(1), []interface{}{"t1", int64(2)}}, {int64(2), []interface{}{}}}},
+		},
+		{
+			"arrayLines-header-types",
+			SQLQuery{ResultFormat: SQLResultArrayLines, Header: true, TypesHeader: true},
+			"[\"b\",\"a\"]\n[\"STRING\",\"FLOAT\"]\n[\"x\",1]\n\n",
+			&SQLResult{Columns: []string{"b", "a"}, Types: []string{"STRING", "FLOAT"}, Rows: []SQLRow{{"x", 1.0}}},
+		},
+		{
+			"csv-header-sqltypes",
+			SQLQuery{ResultFormat: SQLResultCSV, Header: true, SQLTypesHeader: true},
+			"__time,b,a,c\nTIMESTAMP,VARCHAR,BIGINT,BOOLEAN\n2020-01-01T00:00:00.000Z,x,1,true\n2020-01-02T00:00:00.000Z,\"y,z\",,false\n\n",
+			&SQLResult{
+				Columns:  []string{"__time", "b", "a", "c"},
+				SQLTypes: []string{"TIMESTAMP", "VARCHAR", "BIGINT", "BOOLEAN"},
+				Rows: []SQLRow{
+					{"2020-01-01T00:00:00.000Z", "x", int64(1), true},
+					{"2020-01-02T00:00:00.000Z", "y,z", nil, false},
+				},
+			},
+		},
+		{
+			"csv",
+			SQLQuery{ResultFormat: SQLResultCSV},
+			"x,1\n",
+			&SQLResult{Rows: []SQLRow{{"x", "1"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSQLResult(&tt.query, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("decodeSQLResult() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeSQLResult() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLResult_Decode(t *testing.T) {
+	type row struct {
+		Time    time.Time `json:"__time"`
+		Channel string    `json:"channel"`
+		Count   int64     `json:"cnt"`
+		Avg     *float64  `json:"avg"`
+	}
+	result := &SQLResult{
+		Columns: []string{"__time", "channel", "cnt", "avg"},
+		Rows: []SQLRow{
+			{"2020-01-01T00:00:00.000Z", "#en", int64(3), 1.5},
+			{"2020-01-02T00:00:00.000Z", "#zh", int64(1), nil},
+		},
+	}
+	var got []row
+	if err := result.Decode(&got); err != nil {
+		t.Fatalf("SQLResult.Decode() error = %v", err)
+	}
+	avg := 1.5
+	want := []row{
+		{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "#en", 3, &avg},
+		{time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), "#zh", 1, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SQLResult.Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClient_QuerySQL(t *testing.T) {
+	var gotPath, gotRawQuery string
+	var gotQuery SQLQuery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotRawQuery = r.URL.Path, r.URL.RawQuery
+		json.NewDecoder(r.Body).Decode(&gotQuery)
+		w.Write([]byte(`[{"cnt":3}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), Debug: true}
+	query := &SQLQuery{
+		Query:      "SELECT COUNT(*) AS cnt FROM wikipedia WHERE channel = ?",
+		Parameters: []SQLParameter{SQLParam("#en.wikipedia")},
+	}
+	got, err := client.QuerySQL(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Client.QuerySQL() error = %v", err)
+	}
+	if gotPath != DefaultEndPoint+"/sql" || gotRawQuery != "" {
+		t.Errorf("Client.QuerySQL() path = %s?%s, want %s", gotPath, gotRawQuery, DefaultEndPoint+"/sql")
+	}
+	if !reflect.DeepEqual(gotQuery.Parameters, []SQLParameter{{Type: "VARCHAR", Value: "#en.wikipedia"}}) {
+		t.Errorf("Client.QuerySQL() parameters = %v", gotQuery.Parameters)
+	}
+	if row := got.Row(0); row["cnt"] != int64(3) {
+		t.Errorf("Client.QuerySQL() row = %v", row)
+	}
+}
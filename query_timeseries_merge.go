@@ -0,0 +1,38 @@
+package godruid
+
+import "sort"
+
+// mergeQueryResult merge the result rows of the same timestamp, the merged result is sorted by timestamp.
+func (q *QueryTimeseries) mergeQueryResult(oResult []Timeseries) {
+	aggNames := aggregationNames(q.Aggregations)
+	aggTypes := aggregationTypes(q.Aggregations)
+	rowIndex := map[string]int{}
+	for i, item := range q.QueryResult {
+		rowIndex[item.Timestamp] = i
+	}
+
+	for _, item := range oResult {
+		i, ok := rowIndex[item.Timestamp]
+		if !ok {
+			rowIndex[item.Timestamp] = len(q.QueryResult)
+			q.QueryResult = append(q.QueryResult, item)
+			continue
+		}
+		if q.QueryResult[i].Result == nil {
+			q.QueryResult[i].Result = map[string]interface{}{}
+		}
+		for k, v := range mergeEvent(q.QueryResult[i].Result, item.Result, aggNames, aggTypes) {
+			q.QueryResult[i].Result[k] = v
+		}
+	}
+	sort.SliceStable(q.QueryResult, func(i, j int) bool {
+		return q.QueryResult[i].Timestamp < q.QueryResult[j].Timestamp
+	})
+
+	// re compute post aggragation values
+	postAggNames := postAggregationNames(q.PostAggregations)
+	postAggExps := postAggregationExps(q.PostAggregations)
+	for _, item := range q.QueryResult {
+		reComputePostAggs(item.Result, postAggNames, postAggExps)
+	}
+}
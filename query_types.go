@@ -0,0 +1,80 @@
+package godruid
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+var (
+	queryTypesMu sync.RWMutex
+	queryTypes   = map[QueryType]func() Query{
+		GROUPBY:         func() Query { return &QueryGroupBy{} },
+		SEARCH:          func() Query { return &QuerySearch{} },
+		SEGMENTMETADATA: func() Query { return &QuerySegmentMetadata{} },
+		TIMEBOUNDARY:    func() Query { return &QueryTimeBoundary{} },
+		TIMESERIES:      func() Query { return &QueryTimeseries{} },
+		TOPN:            func() Query { return &QueryTopN{} },
+		SELECT:          func() Query { return &QuerySelect{} },
+		SCAN:            func() Query { return &QueryScan{} },
+
+		DATASOURCEMETADATA: func() Query { return &QueryDataSourceMetadata{} },
+	}
+)
+
+// RegisterQueryType register the constructor of an extension query type, so that DecodeQuery can decode it.
+// The constructor of a registered type is replaced.
+func RegisterQueryType(queryType QueryType, newQuery func() Query) {
+	queryTypesMu.Lock()
+	defer queryTypesMu.Unlock()
+	queryTypes[queryType] = newQuery
+}
+
+// DecodeQuery decode the JSON druid query into the query type registered for its `queryType`
+func DecodeQuery(data []byte) (Query, error) {
+	var typed struct {
+		QueryType QueryType `json:"queryType"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+
+	queryTypesMu.RLock()
+	newQuery, ok := queryTypes[typed.QueryType]
+	queryTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("not registered query type: %q", typed.QueryType)
+	}
+
+	query := newQuery()
+	setter, ok := query.(DataSourceSetter)
+	if !ok {
+		if err := json.Unmarshal(data, query); err != nil {
+			return nil, err
+		}
+		return query, nil
+	}
+
+	// the datasource is an interface, decoded by decodeDataSource
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	dsData, hasDataSource := members["dataSource"]
+	delete(members, "dataSource")
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, query); err != nil {
+		return nil, err
+	}
+	if hasDataSource {
+		ds, err := decodeDataSource(dsData)
+		if err != nil {
+			return nil, err
+		}
+		setter.SetDataSource(ds)
+	}
+	return query, nil
+}
@@ -0,0 +1,106 @@
+package godruid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testMovingAverageQuery an extension query type defined out of the builtin ones
+type testMovingAverageQuery struct {
+	QueryType    QueryType              `json:"queryType"`
+	DataSource   DataSource             `json:"dataSource"`
+	Granularity  Granlarity             `json:"granularity"`
+	Intervals    Intervals              `json:"intervals"`
+	Aggregations []Aggregation          `json:"aggregations"`
+	Averagers    []interface{}          `json:"averagers"`
+	Context      map[string]interface{} `json:"context,omitempty"`
+	QueryResult  []GroupbyItem          `json:"-"`
+	RawJSON      []byte                 `json:"-"`
+}
+
+func (q *testMovingAverageQuery) Setup()                      { q.QueryType = "movingAverage" }
+func (q *testMovingAverageQuery) ShouldCache() bool           { return intervalShouldCache(q.Intervals) }
+func (q *testMovingAverageQuery) SetDataSource(ds DataSource) { q.DataSource = ds }
+func (q *testMovingAverageQuery) GetRawJSON() []byte          { return q.RawJSON }
+func (q *testMovingAverageQuery) OnResponse(content []byte) error {
+	q.RawJSON = content
+	return json.Unmarshal(content, &q.QueryResult)
+}
+
+func TestDecodeQuery(t *testing.T) {
+	RegisterQueryType("movingAverage", func() Query { return &testMovingAverageQuery{} })
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Query
+		wantErr bool
+	}{
+		{
+			"builtin",
+			`{"queryType":"timeseries","dataSource":"wiki","granularity":"all","intervals":["2019-01-01/2019-01-02"]}`,
+			&QueryTimeseries{QueryType: TIMESERIES, DataSource: TableName("wiki"), Granularity: "all", Intervals: []string{"2019-01-01/2019-01-02"}},
+			false,
+		},
+		{
+			"extension",
+			`{"queryType":"movingAverage","dataSource":"wiki","granularity":"day","intervals":["2019-01-01/2019-01-02"]}`,
+			&testMovingAverageQuery{QueryType: "movingAverage", DataSource: TableName("wiki"), Granularity: "day", Intervals: []string{"2019-01-01/2019-01-02"}},
+			false,
+		},
+		{
+			"join",
+			`{"queryType":"timeseries","dataSource":{"type":"join","left":"wiki","right":{"type":"query","query":{"queryType":"groupBy","dataSource":{"type":"lookup","lookup":"countries"}}},"rightPrefix":"r.","condition":"\"country\" == \"r.k\"","joinType":"LEFT"}}`,
+			&QueryTimeseries{
+				QueryType: TIMESERIES,
+				DataSource: &JoinDataSource{
+					Type:        "join",
+					Left:        TableName("wiki"),
+					Right:       &QueryDataSource{Type: "query", Query: &QueryGroupBy{QueryType: GROUPBY, DataSource: &LookupDataSource{Type: "lookup", Lookup: "countries"}}},
+					RightPrefix: "r.",
+					Condition:   JoinEquals("country", "r.k"),
+					JoinType:    JoinLeft,
+				},
+			},
+			false,
+		},
+		{"unknown", `{"queryType":"unknown"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeQuery([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeQuery() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Query_extension(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(`[{"version":"v1","timestamp":"2019-01-01T00:00:00.000Z","event":{"count":1,"trailing7":1}}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki", ResultCache: testResultCache{}}
+	for i := 0; i < 2; i++ {
+		query := &testMovingAverageQuery{Granularity: GranDay, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+		if err := client.Query(query); err != nil {
+			t.Fatalf("Client.Query() error = %v", err)
+		}
+		if query.DataSource != TableName("wiki") || len(query.QueryResult) != 1 || query.QueryResult[0].Event["trailing7"] != 1.0 {
+			t.Errorf("Client.Query() = %+v", query)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("extension query sent %d times, want 1 with ResultCache", hits)
+	}
+}
@@ -0,0 +1,90 @@
+package godruid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Headers of druid query responses
+const (
+	HeaderQueryID         = "X-Druid-Query-Id"
+	HeaderSQLQueryID      = "X-Druid-SQL-Query-Id"
+	HeaderResponseContext = "X-Druid-Response-Context"
+	HeaderETag            = "ETag"
+)
+
+// ResponseMeta metadata of a druid query response
+type ResponseMeta struct {
+	// QueryID `queryId` or `sqlQueryId` responded by druid
+	QueryID string
+	ETag    string
+	// Context parsed response context, fields are empty when druid gives no response context
+	Context ResponseContext
+	// RawContext the raw `X-Druid-Response-Context` header
+	RawContext string
+	// Cached whether the result is loaded from Client.ResultCache, other fields are empty when it is true
+	Cached bool
+}
+
+// ResponseContext response context of druid query, given by the `X-Druid-Response-Context` header
+type ResponseContext struct {
+	// UncoveredIntervals intervals of the query not covered by any segment,
+	// given only when `uncoveredIntervalsLimit` is set in the query context
+	UncoveredIntervals           []string `json:"uncoveredIntervals,omitempty"`
+	UncoveredIntervalsOverflowed bool     `json:"uncoveredIntervalsOverflowed,omitempty"`
+	// MissingSegments segments not found by the data nodes, the result is partial when it is not empty.
+	// They are replaced by the segments missed by the requery when Client.RequeryPartial is set.
+	MissingSegments []SegmentDescriptor `json:"missingSegments,omitempty"`
+	// Truncated whether the response context is truncated by druid for its size
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SegmentDescriptor descriptor of a druid segment
+type SegmentDescriptor struct {
+	Interval  string `json:"itvl"`
+	Version   string `json:"ver"`
+	Partition int    `json:"part"`
+}
+
+// newResponseMeta parse the metadata from the response headers, an unparsable response context is kept only in RawContext.
+func newResponseMeta(resp *http.Response) *ResponseMeta {
+	meta := &ResponseMeta{
+		QueryID:    resp.Header.Get(HeaderQueryID),
+		ETag:       resp.Header.Get(HeaderETag),
+		RawContext: resp.Header.Get(HeaderResponseContext),
+	}
+	if meta.QueryID == "" {
+		meta.QueryID = resp.Header.Get(HeaderSQLQueryID)
+	}
+	if meta.RawContext != "" {
+		json.Unmarshal([]byte(meta.RawContext), &meta.Context)
+	}
+	return meta
+}
+
+// meta metadata of the call's response, nil when no response received
+func (call *Call) meta() *ResponseMeta {
+	if call == nil {
+		return nil
+	}
+	return call.Meta
+}
+
+// MissingSegmentsError error of the query whose result is partial for missing segments,
+// returned when Client.MissingSegmentsAsError is set.
+type MissingSegmentsError struct {
+	QueryID         string
+	MissingSegments []SegmentDescriptor
+}
+
+func (e *MissingSegmentsError) Error() string {
+	return fmt.Sprintf("druid query %s: %d segments missing, result is partial", e.QueryID, len(e.MissingSegments))
+}
+
+// IsMissingSegments whether err is caused by missing segments
+func IsMissingSegments(err error) bool {
+	var e *MissingSegmentsError
+	return errors.As(err, &e)
+}
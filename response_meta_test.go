@@ -0,0 +1,51 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_QueryMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderQueryID, "q1")
+		w.Header().Set(HeaderETag, `"abc"`)
+		w.Header().Set(HeaderResponseContext, `{"uncoveredIntervals":["2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z"],"uncoveredIntervalsOverflowed":false,"missingSegments":[{"itvl":"2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z","ver":"v1","part":0}]}`)
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`))
+	}))
+	defer server.Close()
+
+	intervals := []string{"2019-01-01T00:00:00Z/2019-01-03T00:00:00Z"}
+	wantContext := ResponseContext{
+		UncoveredIntervals: []string{"2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z"},
+		MissingSegments:    []SegmentDescriptor{{Interval: "2019-01-01T00:00:00.000Z/2019-01-02T00:00:00.000Z", Version: "v1"}},
+	}
+
+	client := &Client{Url: server.URL, HttpClient: server.Client()}
+	meta, err := client.QueryMeta(context.Background(), &QueryTimeseries{Granularity: GranAll, Intervals: intervals})
+	if err != nil {
+		t.Fatalf("Client.QueryMeta() error = %v", err)
+	}
+	if meta.QueryID != "q1" || meta.ETag != `"abc"` || meta.Cached {
+		t.Errorf("Client.QueryMeta() = %+v", meta)
+	}
+	if !reflect.DeepEqual(meta.Context, wantContext) {
+		t.Errorf("Client.QueryMeta() context = %+v, want %+v", meta.Context, wantContext)
+	}
+
+	cache := testResultCache{}
+	client = &Client{Url: server.URL, HttpClient: server.Client(), ResultCache: cache, MissingSegmentsAsError: true}
+	query := &QueryTimeseries{Granularity: GranAll, Intervals: intervals}
+	meta, err = client.QueryMeta(context.Background(), query)
+	if !IsMissingSegments(err) {
+		t.Fatalf("Client.QueryMeta() error = %v, want missing segments", err)
+	}
+	if meta == nil || len(meta.Context.MissingSegments) != 1 {
+		t.Errorf("Client.QueryMeta() = %+v, want meta with missing segments", meta)
+	}
+	if len(cache) != 0 || len(query.QueryResult) != 0 {
+		t.Errorf("partial result is cached or loaded: %v, %v", cache, query.QueryResult)
+	}
+}
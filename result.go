@@ -0,0 +1,129 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// Result result of a query done by Client.Do
+type Result struct {
+	// Query the copy of the spec which is sent to druid, with the result stored into it
+	Query Query
+	// Meta metadata of the druid response
+	Meta *ResponseMeta
+}
+
+// Do query druid with the spec and return the result, the spec is left untouched,
+// so it can be shared by goroutines or kept as a template. See QueryContext for ctx's effect.
+func (c *Client) Do(ctx context.Context, spec Query) (*Result, error) {
+	query := cloneQuery(spec)
+	call, err := c.queryCall(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Query: query, Meta: call.Meta}, nil
+}
+
+// cloneQuery shallow copy of the query struct without its result
+func cloneQuery(spec Query) Query {
+	v := reflect.ValueOf(spec)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return spec
+	}
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+	for _, name := range []string{"QueryResult", "RawJSON"} {
+		if f := clone.Elem().FieldByName(name); f.IsValid() && f.CanSet() {
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+	return clone.Interface().(Query)
+}
+
+// Raw the raw JSON result responded by druid, it is the merged result after requery(Client.RequeryPartial)
+func (r *Result) Raw() []byte {
+	return r.Query.GetRawJSON()
+}
+
+// Decode unmarshal the raw JSON result into v
+func (r *Result) Decode(v interface{}) error {
+	return json.Unmarshal(r.Raw(), v)
+}
+
+// Rows count of the result rows
+func (r *Result) Rows() int {
+	return resultRows(r.Query)
+}
+
+// GroupBy result of QueryGroupBy, nil for other queries
+func (r *Result) GroupBy() []GroupbyItem {
+	if q, ok := r.Query.(*QueryGroupBy); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// Timeseries result of QueryTimeseries, nil for other queries
+func (r *Result) Timeseries() []Timeseries {
+	if q, ok := r.Query.(*QueryTimeseries); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// TopN result of QueryTopN, nil for other queries
+func (r *Result) TopN() []TopNItem {
+	if q, ok := r.Query.(*QueryTopN); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// Search result of QuerySearch, nil for other queries
+func (r *Result) Search() []SearchItem {
+	if q, ok := r.Query.(*QuerySearch); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// SegmentMetadata result of QuerySegmentMetadata, nil for other queries
+func (r *Result) SegmentMetadata() []SegmentMetaData {
+	if q, ok := r.Query.(*QuerySegmentMetadata); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// TimeBoundary result of QueryTimeBoundary, nil for other queries
+func (r *Result) TimeBoundary() []TimeBoundaryItem {
+	if q, ok := r.Query.(*QueryTimeBoundary); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// DataSourceMetadata result of QueryDataSourceMetadata, nil for other queries
+func (r *Result) DataSourceMetadata() []DataSourceMetadataItem {
+	if q, ok := r.Query.(*QueryDataSourceMetadata); ok {
+		return q.QueryResult
+	}
+	return nil
+}
+
+// Select result of QuerySelect, nil for other queries
+func (r *Result) Select() *SelectBlob {
+	if q, ok := r.Query.(*QuerySelect); ok {
+		return &q.QueryResult
+	}
+	return nil
+}
+
+// Scan result of QueryScan, nil for other queries
+func (r *Result) Scan() []ScanBlob {
+	if q, ok := r.Query.(*QueryScan); ok {
+		return q.QueryResult
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClient_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`))
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	spec := &QueryTimeseries{Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+	origin := *spec
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Do(context.Background(), spec)
+			if err != nil {
+				t.Errorf("Client.Do() error = %v", err)
+				return
+			}
+			want := []Timeseries{{Timestamp: "2019-01-01T00:00:00.000Z", Result: map[string]interface{}{"count": 1.0}}}
+			if !reflect.DeepEqual(res.Timeseries(), want) || res.GroupBy() != nil || res.Rows() != 1 {
+				t.Errorf("Client.Do() result = %v", res.Timeseries())
+			}
+			var raw []map[string]interface{}
+			if err := res.Decode(&raw); err != nil || len(raw) != 1 {
+				t.Errorf("Result.Decode() = %v, %v", raw, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(*spec, origin) {
+		t.Errorf("Client.Do() modified spec to %+v", spec)
+	}
+}
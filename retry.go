@@ -0,0 +1,122 @@
+package godruid
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy policy for retrying failed druid queries.
+// Druid queries are read only, so a query is retried with the same `queryId` for all attempts,
+// which let the broker logs and request logs of all attempts line up.
+type RetryPolicy struct {
+	// MaxAttempts max attempts count including the first one, no retry when <= 1
+	MaxAttempts int
+	// InitialBackoff wait duration before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff upper limit of the wait duration between two attempts
+	MaxBackoff time.Duration
+	// Multiplier backoff grows by Multiplier after each attempt, default to 2 when <= 1
+	Multiplier float64
+	// Jitter randomize the backoff by the fraction [0, 1), as backoff * (1 - Jitter*random)
+	Jitter float64
+	// RetryStatus http status codes to retry
+	RetryStatus []int
+	// RetryErrorCodes druid error codes to retry, matched with `error` or `errorCode` of druid error response
+	RetryErrorCodes []string
+	// RetryErrorClasses druid error classes to retry, matched with `errorClass` of druid error response
+	RetryErrorClasses []string
+	// RetryNetworkErrors retry on connection errors and timeouts
+	RetryNetworkErrors bool
+}
+
+// DefaultRetryPolicy policy retries capacity exceeded(429), unavailable(503) and gateway timeout(504) for 3 attempts.
+// Druid's query timeouts are not retried, for they would time out again.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:        3,
+		InitialBackoff:     200 * time.Millisecond,
+		MaxBackoff:         5 * time.Second,
+		Multiplier:         2,
+		Jitter:             0.2,
+		RetryStatus:        []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryErrorCodes:    []string{ErrCodeQueryCapacityExceeded},
+		RetryNetworkErrors: true,
+	}
+}
+
+// Backoff wait duration before the attempt(starts from 1) retried.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff *= 1 - p.Jitter*rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// shouldRetry whether the failed attempt should be retried,
+// the body of the response is read and replaced for decoding the druid error.
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.RetryNetworkErrors && isBrokerFailure(err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return false
+	}
+	druidErr := readDruidError(resp)
+	// druid responds its query timeouts with 504 as well, they are retried only by RetryErrorCodes
+	druidTimeout := resp.StatusCode == http.StatusGatewayTimeout && druidErr.fromDruid()
+	for _, status := range p.RetryStatus {
+		if resp.StatusCode == status && !druidTimeout {
+			return true
+		}
+	}
+	for _, code := range p.RetryErrorCodes {
+		if code == druidErr.Code || code == druidErr.ErrorCode {
+			return true
+		}
+	}
+	for _, class := range p.RetryErrorClasses {
+		if class == druidErr.ErrorClass {
+			return true
+		}
+	}
+	return false
+}
+
+// queryWithRetry send the query request and retry it by c.Retry.
+func (c *Client) queryWithRetry(ctx context.Context, endPoint, queryID string, req []byte) (*http.Response, error) {
+	policy := c.Retry
+	dispatched := func(baseURL string) { c.setBroker(queryID, baseURL) }
+	for attempt := 1; ; attempt++ {
+		resp, err := c.request(ctx, http.MethodPost, endPoint, req, dispatched)
+		if policy == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		backoff := policy.Backoff(attempt)
+		if err != nil {
+			c.logger().Warnf("[%s] query %s attempt %d failed, retry after %v: %v", "Client.QueryRaw", queryID, attempt, backoff, err)
+		} else {
+			resp.Body.Close()
+			c.logger().Warnf("[%s] query %s attempt %d failed, retry after %v: %s", "Client.QueryRaw", queryID, attempt, backoff, resp.Status)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package godruid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_QueryRaw_retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		body         string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"ok", 0, http.StatusOK, `[]`, 1, false},
+		{"capacity-exceeded", 2, http.StatusTooManyRequests, `{"error":"Query capacity exceeded"}`, 3, false},
+		{"gateway-timeout", 1, http.StatusGatewayTimeout, `<html>504 Gateway Time-out</html>`, 2, false},
+		{"query-timeout", 1, http.StatusGatewayTimeout, `{"error":"Query timeout"}`, 1, true},
+		{"too-many-failures", 5, http.StatusServiceUnavailable, ``, 3, true},
+		{"not-retryable", 2, http.StatusBadRequest, `{"error":"Unsupported operation"}`, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			queryIDs := map[string]bool{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var query struct {
+					Context map[string]interface{} `json:"context"`
+				}
+				json.NewDecoder(r.Body).Decode(&query)
+				queryIDs[query.Context[QUERYID].(string)] = true
+				attempts++
+				if attempts <= tt.failures {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.InitialBackoff = time.Millisecond
+			client := &Client{Url: server.URL, HttpClient: server.Client(), Retry: policy}
+			_, err := client.QueryRaw([]byte(`{"queryType":"timeseries"}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.QueryRaw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Client.QueryRaw() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(queryIDs) != 1 {
+				t.Errorf("Client.QueryRaw() queryIds = %v, want the same one for all attempts", queryIDs)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("RetryPolicy.Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package godruid
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunningQuery a query the client has in flight
+type RunningQuery struct {
+	QueryID    string
+	SQL        bool
+	QueryType  string // empty for sql queries
+	DataSource string // empty for sql queries
+	Start      time.Time
+}
+
+type runningQuery struct {
+	RunningQuery
+	api    queryAPI
+	cancel context.CancelFunc
+	broker string // base url of the druid node the query is sent to
+}
+
+// Running queries this client has in flight, in order of their start time.
+// A query is in flight from sending until its response is fully read.
+func (c *Client) Running() []RunningQuery {
+	c.mu.RLock()
+	ret := make([]RunningQuery, 0, len(c.running))
+	for _, q := range c.running {
+		ret = append(ret, q.RunningQuery)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Start.Before(ret[j].Start) })
+	return ret
+}
+
+// Cancel cancel the query on druid broker by its `queryId`(or `sqlQueryId` of sql queries),
+// the call of the query is aborted when it is in flight of this client.
+func (c *Client) Cancel(queryID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), CancelTimeout)
+	defer cancel()
+	return c.CancelContext(ctx, queryID)
+}
+
+// CancelContext cancel the query like Cancel, the cancel request is aborted when ctx is done.
+func (c *Client) CancelContext(ctx context.Context, queryID string) error {
+	api := nativeAPI
+	c.mu.RLock()
+	q, running := c.running[queryID]
+	c.mu.RUnlock()
+	if running {
+		api = q.api
+	}
+
+	err := c.cancelQuery(ctx, api, queryID)
+	if running {
+		q.cancel()
+	}
+	return err
+}
+
+// track register the query as running until the returned untrack func is called,
+// the returned context is canceled by Cancel or untrack. Queries without id are not registered.
+func (c *Client) track(ctx context.Context, api queryAPI, queryID string, req []byte) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if queryID == "" {
+		return ctx, cancel
+	}
+	q := &runningQuery{
+		RunningQuery: RunningQuery{QueryID: queryID, SQL: api == sqlAPI, Start: time.Now()},
+		api:          api,
+		cancel:       cancel,
+	}
+	if api == nativeAPI {
+		q.QueryType, q.DataSource = requestInfo(req)
+	}
+
+	c.mu.Lock()
+	if c.running == nil {
+		c.running = map[string]*runningQuery{}
+	}
+	c.running[queryID] = q
+	c.mu.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			if c.running[queryID] == q {
+				delete(c.running, queryID)
+			}
+			c.mu.Unlock()
+			cancel()
+		})
+	}
+}
+
+// trackedBody response body untracking the running query when closed
+type trackedBody struct {
+	io.ReadCloser
+	untrack func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.untrack()
+	return err
+}
+
+// setBroker record the base url of the druid node the running query is sent to
+func (c *Client) setBroker(queryID, baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if q, ok := c.running[queryID]; ok {
+		q.broker = baseURL
+	}
+}
+
+// brokerOf the base url of the druid node the running query is sent to, empty when the query is not running
+func (c *Client) brokerOf(queryID string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if q, ok := c.running[queryID]; ok {
+		return q.broker
+	}
+	return ""
+}
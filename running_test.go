@@ -0,0 +1,105 @@
+package godruid
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Cancel(t *testing.T) {
+	deleted := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted <- strings.TrimPrefix(r.URL.Path, DefaultEndPoint+"/")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki"}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.QueryContext(context.Background(), &QueryTimeseries{Granularity: GranAll})
+	}()
+
+	var running []RunningQuery
+	for i := 0; i < 100 && len(running) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		running = client.Running()
+	}
+	if len(running) != 1 {
+		t.Fatalf("Client.Running() = %v, want 1 query", running)
+	}
+	q := running[0]
+	if len(q.QueryID) != 36 || q.QueryType != "timeseries" || q.DataSource != "wiki" || q.SQL || q.Start.IsZero() {
+		t.Errorf("Client.Running() = %+v", q)
+	}
+
+	if err := client.Cancel(q.QueryID); err != nil {
+		t.Fatalf("Client.Cancel() error = %v", err)
+	}
+	if id := <-deleted; id != q.QueryID {
+		t.Errorf("Client.Cancel() canceled %s, want %s", id, q.QueryID)
+	}
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Errorf("Client.QueryContext() error = nil, want canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Client.QueryContext() not aborted by Client.Cancel()")
+	}
+	if running := client.Running(); len(running) != 0 {
+		t.Errorf("Client.Running() = %v after cancel, want empty", running)
+	}
+}
+
+func TestClient_Cancel_brokers(t *testing.T) {
+	type hit struct {
+		server int
+		method string
+	}
+	hits := make(chan hit, 10)
+	newServer := func(n int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits <- hit{n, r.Method}
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			ioutil.ReadAll(r.Body)
+			<-r.Context().Done()
+		}))
+	}
+	servers := []*httptest.Server{newServer(0), newServer(1)}
+	defer servers[0].Close()
+	defer servers[1].Close()
+
+	client := &Client{Brokers: NewBrokerPool(servers[0].URL, servers[1].URL), HttpClient: http.DefaultClient, DataSource: "wiki"}
+	for i := 0; i < 2; i++ {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- client.QueryContext(context.Background(), &QueryTimeseries{Granularity: GranAll})
+		}()
+		sent := <-hits
+		running := client.Running()
+		if len(running) != 1 {
+			t.Fatalf("Client.Running() = %v, want 1 query", running)
+		}
+
+		if err := client.Cancel(running[0].QueryID); err != nil {
+			t.Fatalf("Client.Cancel() error = %v", err)
+		}
+		if canceled := <-hits; canceled != (hit{sent.server, http.MethodDelete}) {
+			t.Errorf("Client.Cancel() sent to %+v, want server %d which runs the query", canceled, sent.server)
+		}
+		<-errCh
+	}
+}
@@ -0,0 +1,655 @@
+package godruid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Smile binary JSON format(https://github.com/FasterXML/smile-format-specification),
+// only transcoding from and to JSON is implemented.
+
+var smileHeader = []byte{':', ')', '\n'}
+
+const (
+	smileFlagSharedNames  = 0x01
+	smileFlagSharedValues = 0x02
+
+	smileMaxShared = 1024
+
+	smileEmptyString = 0x20
+	smileNull        = 0x21
+	smileFalse       = 0x22
+	smileTrue        = 0x23
+	smileInt32       = 0x24
+	smileInt64       = 0x25
+	smileBigInteger  = 0x26
+	smileFloat32     = 0x28
+	smileFloat64     = 0x29
+	smileBigDecimal  = 0x2A
+	smileLongASCII   = 0xE0
+	smileLongUnicode = 0xE4
+	smileBinary7Bit  = 0xE8
+	smileStartArray  = 0xF8
+	smileEndArray    = 0xF9
+	smileStartObject = 0xFA
+	smileEndObject   = 0xFB
+	smileEndString   = 0xFC
+	smileRawBinary   = 0xFD
+	smileEndContent  = 0xFF
+
+	smileKeyLongName = 0x34
+)
+
+var errSmileTruncated = errors.New("smile: unexpected end of data")
+
+// smileToJSON transcode a smile document into JSON
+func smileToJSON(data []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	if err := transcodeSmile(bytes.NewReader(data), out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// transcodeSmile transcode the smile document read from r into JSON written to w as it is read
+func transcodeSmile(r io.Reader, w io.Writer) error {
+	d := &smileDecoder{in: bufio.NewReader(r), out: bufio.NewWriter(w)}
+	if header, _ := d.in.Peek(len(smileHeader)); bytes.Equal(header, smileHeader) {
+		d.in.Discard(len(smileHeader))
+		d.pos = len(smileHeader)
+		flags, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if version := flags >> 4; version != 0 {
+			return fmt.Errorf("smile: not support version %d", version)
+		}
+		d.sharedNames = flags&smileFlagSharedNames != 0
+		d.sharedValues = flags&smileFlagSharedValues != 0
+	}
+
+	b, err := d.byte()
+	if err != nil {
+		return err
+	}
+	if err := d.value(b); err != nil {
+		return err
+	}
+	return d.out.Flush()
+}
+
+type smileDecoder struct {
+	in           *bufio.Reader
+	pos          int
+	out          *bufio.Writer
+	sharedNames  bool
+	sharedValues bool
+	names        []string
+	values       []string
+}
+
+func (d *smileDecoder) byte() (byte, error) {
+	b, err := d.in.ReadByte()
+	if err != nil {
+		return 0, truncated(err)
+	}
+	d.pos++
+	return b, nil
+}
+
+func (d *smileDecoder) bytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errSmileTruncated
+	}
+	// not allocated by n, for the length is not trusted before the data is read
+	b, err := ioutil.ReadAll(io.LimitReader(d.in, int64(n)))
+	d.pos += len(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < n {
+		return nil, errSmileTruncated
+	}
+	return b, nil
+}
+
+// untilEndString bytes before the end-of-string marker, the marker is skipped
+func (d *smileDecoder) untilEndString() ([]byte, error) {
+	b, err := d.in.ReadBytes(smileEndString)
+	d.pos += len(b)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	return b[:len(b)-1], nil
+}
+
+// truncated errSmileTruncated for the end of data
+func truncated(err error) error {
+	if err == io.EOF {
+		return errSmileTruncated
+	}
+	return err
+}
+
+// vint unsigned variable length integer, 7 bits per byte with the last byte marked by 0x80 holding 6 bits
+func (d *smileDecoder) vint() (uint64, error) {
+	var v uint64
+	for i := 0; i < 10; i++ {
+		b, err := d.byte()
+		if err != nil {
+			return 0, err
+		}
+		if b&0x80 != 0 {
+			return v<<6 | uint64(b&0x3F), nil
+		}
+		v = v<<7 | uint64(b)
+	}
+	return 0, errors.New("smile: vint too long")
+}
+
+// fixed7Bit fixed length of 7 bit bytes as an unsigned integer
+func (d *smileDecoder) fixed7Bit(n int) (uint64, error) {
+	b, err := d.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<7 | uint64(c&0x7F)
+	}
+	return v, nil
+}
+
+// binary7Bit data of length n encoded as 7 bit bytes, the last partial group is right aligned
+func (d *smileDecoder) binary7Bit(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errSmileTruncated
+	}
+	var ret []byte
+	var acc uint64
+	var bits uint
+	for len(ret) < n {
+		remain := uint((n - len(ret)) * 8)
+		chunk := uint(7)
+		if remain-bits < 7 {
+			chunk = remain - bits
+		}
+		b, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		acc = acc<<chunk | uint64(b&0x7F)
+		bits += chunk
+		for bits >= 8 {
+			bits -= 8
+			ret = append(ret, byte(acc>>bits))
+		}
+	}
+	return ret, nil
+}
+
+func (d *smileDecoder) value(b byte) error {
+	switch {
+	case b >= 0x01 && b <= 0x1F:
+		return d.sharedValue(int(b) - 1)
+	case b == smileEmptyString:
+		d.out.WriteString(`""`)
+	case b == smileNull:
+		d.out.WriteString("null")
+	case b == smileFalse:
+		d.out.WriteString("false")
+	case b == smileTrue:
+		d.out.WriteString("true")
+	case b == smileInt32 || b == smileInt64:
+		v, err := d.vint()
+		if err != nil {
+			return err
+		}
+		d.out.WriteString(strconv.FormatInt(zigzagDecode(v), 10))
+	case b == smileBigInteger:
+		i, err := d.bigInt()
+		if err != nil {
+			return err
+		}
+		d.out.WriteString(i.String())
+	case b == smileFloat32:
+		v, err := d.fixed7Bit(5)
+		if err != nil {
+			return err
+		}
+		d.writeFloat(float64(math.Float32frombits(uint32(v))), 32)
+	case b == smileFloat64:
+		v, err := d.fixed7Bit(10)
+		if err != nil {
+			return err
+		}
+		d.writeFloat(math.Float64frombits(v), 64)
+	case b == smileBigDecimal:
+		scale, err := d.vint()
+		if err != nil {
+			return err
+		}
+		i, err := d.bigInt()
+		if err != nil {
+			return err
+		}
+		d.out.WriteString(i.String())
+		if s := zigzagDecode(scale); s != 0 {
+			d.out.WriteString("E" + strconv.FormatInt(-s, 10))
+		}
+	case b >= 0x40 && b <= 0xBF:
+		n := int(b&0x1F) + [4]int{1, 33, 2, 34}[(b-0x40)>>5]
+		s, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		if d.sharedValues {
+			d.values = addShared(d.values, string(s))
+		}
+		writeJSONString(d.out, s)
+	case b >= 0xC0 && b <= 0xDF:
+		d.out.WriteString(strconv.FormatInt(zigzagDecode(uint64(b&0x1F)), 10))
+	case b == smileLongASCII || b == smileLongUnicode:
+		s, err := d.untilEndString()
+		if err != nil {
+			return err
+		}
+		writeJSONString(d.out, s)
+	case b == smileBinary7Bit || b == smileRawBinary:
+		n, err := d.vint()
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if b == smileBinary7Bit {
+			data, err = d.binary7Bit(int(n))
+		} else {
+			data, err = d.bytes(int(n))
+		}
+		if err != nil {
+			return err
+		}
+		d.out.WriteString(`"` + base64.StdEncoding.EncodeToString(data) + `"`)
+	case b >= 0xEC && b <= 0xEF:
+		low, err := d.byte()
+		if err != nil {
+			return err
+		}
+		return d.sharedValue(int(b&0x03)<<8 | int(low))
+	case b == smileStartArray:
+		return d.array()
+	case b == smileStartObject:
+		return d.object()
+	default:
+		return fmt.Errorf("smile: unexpected value token 0x%02X at %d", b, d.pos-1)
+	}
+	return nil
+}
+
+func (d *smileDecoder) bigInt() (*big.Int, error) {
+	n, err := d.vint()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.binary7Bit(int(n))
+	if err != nil {
+		return nil, err
+	}
+	i := new(big.Int).SetBytes(data)
+	if len(data) > 0 && data[0]&0x80 != 0 {
+		// two's complement
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(len(data)*8)))
+	}
+	return i, nil
+}
+
+func (d *smileDecoder) writeFloat(f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		d.out.WriteString("null")
+		return
+	}
+	d.out.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+func (d *smileDecoder) sharedValue(i int) error {
+	if i >= len(d.values) {
+		return fmt.Errorf("smile: invalid shared value reference %d", i)
+	}
+	writeJSONString(d.out, []byte(d.values[i]))
+	return nil
+}
+
+func (d *smileDecoder) array() error {
+	d.out.WriteByte('[')
+	for i := 0; ; i++ {
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if b == smileEndArray {
+			d.out.WriteByte(']')
+			return nil
+		}
+		if i > 0 {
+			d.out.WriteByte(',')
+		}
+		if err := d.value(b); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *smileDecoder) object() error {
+	d.out.WriteByte('{')
+	for i := 0; ; i++ {
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if b == smileEndObject {
+			d.out.WriteByte('}')
+			return nil
+		}
+		if i > 0 {
+			d.out.WriteByte(',')
+		}
+		name, err := d.key(b)
+		if err != nil {
+			return err
+		}
+		writeJSONString(d.out, []byte(name))
+		d.out.WriteByte(':')
+
+		if b, err = d.byte(); err != nil {
+			return err
+		}
+		if err := d.value(b); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *smileDecoder) key(b byte) (string, error) {
+	var name []byte
+	var err error
+	switch {
+	case b == smileEmptyString:
+		return "", nil
+	case b >= 0x30 && b <= 0x33:
+		low, err := d.byte()
+		if err != nil {
+			return "", err
+		}
+		return d.sharedName(int(b&0x03)<<8 | int(low))
+	case b >= 0x40 && b <= 0x7F:
+		return d.sharedName(int(b & 0x3F))
+	case b == smileKeyLongName:
+		name, err = d.untilEndString()
+	case b >= 0x80 && b <= 0xBF:
+		name, err = d.bytes(int(b&0x3F) + 1)
+	case b >= 0xC0 && b <= 0xF7:
+		name, err = d.bytes(int(b&0x3F) + 2)
+	default:
+		return "", fmt.Errorf("smile: unexpected key token 0x%02X at %d", b, d.pos-1)
+	}
+	if err != nil {
+		return "", err
+	}
+	if d.sharedNames {
+		d.names = addShared(d.names, string(name))
+	}
+	return string(name), nil
+}
+
+func (d *smileDecoder) sharedName(i int) (string, error) {
+	if i >= len(d.names) {
+		return "", fmt.Errorf("smile: invalid shared name reference %d", i)
+	}
+	return d.names[i], nil
+}
+
+// addShared add s into the shared strings table, the table is cleared when it is full
+func addShared(table []string, s string) []string {
+	if len(table) == smileMaxShared {
+		table = table[:0]
+	}
+	return append(table, s)
+}
+
+func zigzagDecode(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func zigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func writeJSONString(out *bufio.Writer, s []byte) {
+	const hex = "0123456789abcdef"
+	out.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c < 0x20:
+			out.WriteString(`\u00`)
+			out.WriteByte(hex[c>>4])
+			out.WriteByte(hex[c&0x0F])
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+}
+
+// jsonToSmile transcode a JSON document into smile, names and values are not shared.
+func jsonToSmile(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	e := &smileEncoder{out: &bytes.Buffer{}}
+	e.out.Write(smileHeader)
+	e.out.WriteByte(0)
+	if err := e.value(decoder); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("smile: unexpected data after JSON value")
+	}
+	return e.out.Bytes(), nil
+}
+
+type smileEncoder struct {
+	out *bytes.Buffer
+}
+
+func (e *smileEncoder) value(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch v := token.(type) {
+	case json.Delim:
+		switch v {
+		case '[':
+			e.out.WriteByte(smileStartArray)
+			for decoder.More() {
+				if err := e.value(decoder); err != nil {
+					return err
+				}
+			}
+			e.out.WriteByte(smileEndArray)
+		case '{':
+			e.out.WriteByte(smileStartObject)
+			for decoder.More() {
+				token, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				e.key(token.(string))
+				if err := e.value(decoder); err != nil {
+					return err
+				}
+			}
+			e.out.WriteByte(smileEndObject)
+		}
+		// consume the closing delim
+		_, err = decoder.Token()
+		return err
+	case nil:
+		e.out.WriteByte(smileNull)
+	case bool:
+		if v {
+			e.out.WriteByte(smileTrue)
+		} else {
+			e.out.WriteByte(smileFalse)
+		}
+	case string:
+		e.string(v)
+	case json.Number:
+		e.number(v)
+	}
+	return nil
+}
+
+func (e *smileEncoder) key(name string) {
+	n := len(name)
+	ascii := isASCII(name)
+	switch {
+	case n == 0:
+		e.out.WriteByte(smileEmptyString)
+		return
+	case ascii && n <= 64:
+		e.out.WriteByte(0x80 + byte(n-1))
+	case !ascii && n <= 57:
+		e.out.WriteByte(0xC0 + byte(n-2))
+	default:
+		e.out.WriteByte(smileKeyLongName)
+		e.out.WriteString(name)
+		e.out.WriteByte(smileEndString)
+		return
+	}
+	e.out.WriteString(name)
+}
+
+func (e *smileEncoder) string(s string) {
+	n := len(s)
+	ascii := isASCII(s)
+	switch {
+	case n == 0:
+		e.out.WriteByte(smileEmptyString)
+		return
+	case ascii && n <= 32:
+		e.out.WriteByte(0x40 + byte(n-1))
+	case ascii && n <= 64:
+		e.out.WriteByte(0x60 + byte(n-33))
+	case !ascii && n <= 33:
+		e.out.WriteByte(0x80 + byte(n-2))
+	case !ascii && n <= 65:
+		e.out.WriteByte(0xA0 + byte(n-34))
+	default:
+		if ascii {
+			e.out.WriteByte(smileLongASCII)
+		} else {
+			e.out.WriteByte(smileLongUnicode)
+		}
+		e.out.WriteString(s)
+		e.out.WriteByte(smileEndString)
+		return
+	}
+	e.out.WriteString(s)
+}
+
+func (e *smileEncoder) number(n json.Number) {
+	if i, err := n.Int64(); err == nil {
+		switch {
+		case i >= -16 && i <= 15:
+			e.out.WriteByte(0xC0 + byte(zigzagEncode(i)))
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			e.out.WriteByte(smileInt32)
+			e.vint(zigzagEncode(i))
+		default:
+			e.out.WriteByte(smileInt64)
+			e.vint(zigzagEncode(i))
+		}
+		return
+	}
+	if i, ok := new(big.Int).SetString(n.String(), 10); ok {
+		e.out.WriteByte(smileBigInteger)
+		e.bigInt(i)
+		return
+	}
+
+	f, _ := n.Float64()
+	bits := math.Float64bits(f)
+	e.out.WriteByte(smileFloat64)
+	for shift := 63; shift >= 0; shift -= 7 {
+		e.out.WriteByte(byte(bits>>uint(shift)) & 0x7F)
+	}
+}
+
+// vint see smileDecoder.vint
+func (e *smileEncoder) vint(v uint64) {
+	var buf [10]byte
+	i := len(buf) - 1
+	buf[i] = 0x80 | byte(v&0x3F)
+	for v >>= 6; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v & 0x7F)
+	}
+	e.out.Write(buf[i:])
+}
+
+// bigInt two's complement bytes of i, in 7 bit encoding with the length
+func (e *smileEncoder) bigInt(i *big.Int) {
+	var data []byte
+	if i.Sign() >= 0 {
+		data = append([]byte{0}, i.Bytes()...)
+	} else {
+		n := len(i.Bytes()) + 1
+		data = new(big.Int).Add(i, new(big.Int).Lsh(big.NewInt(1), uint(n*8))).Bytes()
+		for len(data) < n {
+			data = append([]byte{0xFF}, data...)
+		}
+	}
+	e.vint(uint64(len(data)))
+
+	var acc uint64
+	var bits uint
+	for j, b := range data {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 7 {
+			bits -= 7
+			e.out.WriteByte(byte(acc>>bits) & 0x7F)
+		}
+		if j == len(data)-1 && bits > 0 {
+			e.out.WriteByte(byte(acc) & (1<<bits - 1))
+		}
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,287 @@
+package godruid
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Span names of the traced operations
+const (
+	SpanQuery       = "druid.query"
+	SpanSQL         = "druid.sql"
+	SpanStream      = "druid.stream"
+	SpanCacheQuery  = "druid.cacheQuery"
+	SpanSlot        = "druid.slot"
+	SpanCacheLookup = "druid.cacheLookup"
+	SpanBroker      = "druid.broker"
+	SpanDecode      = "druid.decode"
+)
+
+// Attribute keys of the http timings(time.Duration) set on SpanBroker spans
+const (
+	AttrDNS      = "http.dns"
+	AttrConnect  = "http.connect"
+	AttrTLS      = "http.tls"
+	AttrTTFB     = "http.ttfb"
+	AttrBodyRead = "http.bodyRead"
+)
+
+// Tracer tracer of druid queries, spans are nested by the context they started with.
+type Tracer interface {
+	// Start start a span as a child of the span carried by ctx, the returned context carries the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span a traced operation
+type Span interface {
+	SetAttribute(key string, value interface{})
+	// End finish the span, err is the error of the operation or nil
+	End(err error)
+}
+
+// NoopTracer tracer doing nothing, it is the default of Client
+type NoopTracer struct{}
+
+// Start implement Tracer
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) End(err error)                              {}
+
+func (c *Client) tracer() Tracer {
+	if c.Tracer == nil {
+		return NoopTracer{}
+	}
+	return c.Tracer
+}
+
+// startSpan start a query span with the query type and datasource of the native query req
+func (c *Client) startSpan(ctx context.Context, name string, req []byte) (context.Context, Span) {
+	ctx, span := c.tracer().Start(ctx, name)
+	if c.Tracer != nil && req != nil {
+		queryType, dataSource := requestInfo(req)
+		span.SetAttribute("druid.queryType", queryType)
+		span.SetAttribute("druid.dataSource", dataSource)
+	}
+	return ctx, span
+}
+
+// endSpan end the query span of the call
+func (c *Client) endSpan(span Span, call *Call, err error) {
+	if call.QueryID != "" {
+		span.SetAttribute("druid.queryId", call.QueryID)
+	}
+	span.SetAttribute("druid.cached", call.Cached)
+	span.End(err)
+}
+
+// traceRaw send the request by queryRaw in a SpanBroker span with the http timings,
+// the span ends when the response body is closed.
+func (c *Client) traceRaw(ctx context.Context, method, baseURL, endPoint string, auth Authenticator, req []byte) (*http.Response, error) {
+	ctx, span := c.tracer().Start(ctx, SpanBroker)
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.url", baseURL+endPoint)
+	timings := &httpTimings{span: span}
+	ctx = httptrace.WithClientTrace(ctx, timings.clientTrace())
+
+	timings.start = time.Now()
+	resp, err := queryRaw(ctx, c.HttpClient, method, baseURL, endPoint, auth, c.wire(ctx), req)
+	if err != nil {
+		timings.end(err)
+		return nil, err
+	}
+	span.SetAttribute("http.statusCode", resp.StatusCode)
+	resp.Body = &tracedBody{ReadCloser: resp.Body, timings: timings, headerAt: time.Now()}
+	return resp, nil
+}
+
+// httpTimings timings of a http request collected by httptrace
+type httpTimings struct {
+	span  Span
+	start time.Time
+
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	attrs        map[string]interface{}
+}
+
+func (t *httpTimings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(&t.dnsStart, AttrDNS) },
+		ConnectStart: func(network, addr string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.since(&t.connectStart, AttrConnect)
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(&t.tlsStart, AttrTLS)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.set("http.reusedConn", info.Reused)
+		},
+		GotFirstResponseByte: func() { t.since(&t.start, AttrTTFB) },
+	}
+}
+
+func (t *httpTimings) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+}
+
+func (t *httpTimings) since(at *time.Time, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		return
+	}
+	t.setLocked(key, time.Since(*at))
+}
+
+func (t *httpTimings) set(key string, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setLocked(key, value)
+}
+
+func (t *httpTimings) setLocked(key string, value interface{}) {
+	if t.attrs == nil {
+		t.attrs = map[string]interface{}{}
+	}
+	t.attrs[key] = value
+}
+
+// end set the collected timings to the span and end it
+func (t *httpTimings) end(err error) {
+	t.mu.Lock()
+	attrs := t.attrs
+	t.attrs = nil
+	t.mu.Unlock()
+	for k, v := range attrs {
+		t.span.SetAttribute(k, v)
+	}
+	t.span.End(err)
+}
+
+// tracedBody response body ending the SpanBroker span when closed
+type tracedBody struct {
+	io.ReadCloser
+	timings  *httpTimings
+	headerAt time.Time
+	readErr  error
+	once     sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.readErr = err
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.timings.set(AttrBodyRead, time.Since(b.headerAt))
+		b.timings.end(b.readErr)
+	})
+	return err
+}
+
+// RecordedSpan span recorded by MemoryTracer
+type RecordedSpan struct {
+	ID         int
+	ParentID   int // 0 for root spans
+	Name       string
+	Start      time.Time
+	End        time.Time // zero when the span is not ended
+	Attributes map[string]interface{}
+	Err        error
+}
+
+// Duration duration of the ended span
+func (s RecordedSpan) Duration() time.Duration {
+	if s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// MemoryTracer tracer recording spans in memory, safe for concurrent use, mainly for tests.
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+type memorySpanKey struct{}
+
+// Start implement Tracer
+func (t *MemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record := &RecordedSpan{ID: len(t.spans) + 1, Name: name, Start: time.Now(), Attributes: map[string]interface{}{}}
+	if parent, ok := ctx.Value(memorySpanKey{}).(*memorySpan); ok && parent.tracer == t {
+		record.ParentID = parent.record.ID
+	}
+	t.spans = append(t.spans, record)
+	span := &memorySpan{tracer: t, record: record}
+	return context.WithValue(ctx, memorySpanKey{}, span), span
+}
+
+// Spans copy of the recorded spans in order of their start
+func (t *MemoryTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ret := make([]RecordedSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		span := *s
+		span.Attributes = map[string]interface{}{}
+		for k, v := range s.Attributes {
+			span.Attributes[k] = v
+		}
+		ret = append(ret, span)
+	}
+	return ret
+}
+
+// Reset drop all recorded spans
+func (t *MemoryTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+type memorySpan struct {
+	tracer *MemoryTracer
+	record *RecordedSpan
+}
+
+func (s *memorySpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.record.Attributes[key] = value
+}
+
+func (s *memorySpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	if s.record.End.IsZero() {
+		s.record.End = time.Now()
+		s.record.Err = err
+	}
+}
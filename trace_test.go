@@ -0,0 +1,116 @@
+package godruid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testGroupByCache struct {
+	selects int
+}
+
+func (c *testGroupByCache) Select(query CacheSelectQuery) []PersistenceRow {
+	c.selects++
+	return nil
+}
+
+func (c *testGroupByCache) Insert(target string, entry PersistenceRow, lifespan time.Duration) error {
+	return nil
+}
+
+func (c *testGroupByCache) InsertBatch(target string, entries []PersistenceRow, lifespan time.Duration) error {
+	return nil
+}
+
+func (c *testGroupByCache) Delete(query CacheSelectQuery) error { return nil }
+func (c *testGroupByCache) Clean(target string) error           { return nil }
+
+func spanChildren(spans []RecordedSpan, parentID int, name string) []RecordedSpan {
+	ret := []RecordedSpan{}
+	for _, s := range spans {
+		if s.ParentID == parentID && s.Name == name {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func TestClient_Tracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"timestamp":"2019-01-01T00:00:00.000Z","result":{"count":1}}]`))
+	}))
+	defer server.Close()
+
+	tracer := &MemoryTracer{}
+	client := &Client{Url: server.URL, HttpClient: server.Client(), DataSource: "wiki", ResultCache: testResultCache{}, Tracer: tracer}
+	query := &QueryTimeseries{Granularity: GranAll, Intervals: []string{"2019-01-01T00:00:00Z/2019-01-02T00:00:00Z"}}
+	if err := client.Query(query); err != nil {
+		t.Fatalf("Client.Query() error = %v", err)
+	}
+
+	spans := tracer.Spans()
+	roots := spanChildren(spans, 0, SpanQuery)
+	if len(roots) != 1 {
+		t.Fatalf("MemoryTracer.Spans() = %v, want 1 root query span", spans)
+	}
+	root := roots[0]
+	if root.Attributes["druid.queryType"] != "timeseries" || root.Attributes["druid.dataSource"] != "wiki" || root.End.IsZero() {
+		t.Errorf("query span = %+v", root)
+	}
+	for _, name := range []string{SpanCacheLookup, SpanBroker, SpanDecode} {
+		if children := spanChildren(spans, root.ID, name); len(children) != 1 {
+			t.Errorf("query span children %s = %v, want 1", name, children)
+		}
+	}
+	broker := spanChildren(spans, root.ID, SpanBroker)[0]
+	for _, attr := range []string{AttrConnect, AttrTTFB, AttrBodyRead} {
+		if _, ok := broker.Attributes[attr].(time.Duration); !ok {
+			t.Errorf("broker span attribute %s = %v, want duration", attr, broker.Attributes[attr])
+		}
+	}
+	if broker.Attributes["http.statusCode"] != http.StatusOK || broker.End.IsZero() {
+		t.Errorf("broker span = %+v", broker)
+	}
+}
+
+func TestQueryGroupBy_CacheQueryContext_trace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	tracer := &MemoryTracer{}
+	cache := &testGroupByCache{}
+	client := &Client{Url: server.URL, HttpClient: server.Client(), GroupByCache: cache, Tracer: tracer}
+	query := &QueryGroupBy{
+		Granularity:  GranAll,
+		Dimensions:   []DimSpec{"os"},
+		Aggregations: []Aggregation{*AggCount("count")},
+		Intervals:    []string{"2019-01-01T00:00:00Z/2019-01-01T02:00:00Z"},
+	}
+	slots, err := query.DistributeIntervalSlots()
+	if err != nil {
+		t.Fatalf("QueryGroupBy.DistributeIntervalSlots() error = %v", err)
+	}
+	if err := query.CacheQueryContext(context.Background(), client, "t", false); err != nil {
+		t.Fatalf("QueryGroupBy.CacheQueryContext() error = %v", err)
+	}
+
+	spans := tracer.Spans()
+	roots := spanChildren(spans, 0, SpanCacheQuery)
+	if len(roots) != 1 {
+		t.Fatalf("MemoryTracer.Spans() = %v, want 1 root cache query span", spans)
+	}
+	slotSpans := spanChildren(spans, roots[0].ID, SpanSlot)
+	if len(slotSpans) != len(slots) || cache.selects != len(slots) {
+		t.Fatalf("slot spans = %d, selects = %d, want %d", len(slotSpans), cache.selects, len(slots))
+	}
+	for _, slot := range slotSpans {
+		if len(spanChildren(spans, slot.ID, SpanCacheLookup)) != 1 || len(spanChildren(spans, slot.ID, SpanQuery)) != 1 {
+			t.Errorf("slot span %v has no cache lookup or query child", slot.Attributes)
+		}
+	}
+}